@@ -6,12 +6,29 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"syscall"
 
+	"github.com/Kiratopat-s/workflow/internal/apikey"
+	"github.com/Kiratopat-s/workflow/internal/approval"
 	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/comment"
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/delegation"
+	"github.com/Kiratopat-s/workflow/internal/escalation"
 	"github.com/Kiratopat-s/workflow/internal/item"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/mail"
+	"github.com/Kiratopat-s/workflow/internal/password"
+	"github.com/Kiratopat-s/workflow/internal/role"
+	"github.com/Kiratopat-s/workflow/internal/rule"
+	"github.com/Kiratopat-s/workflow/internal/session"
+	"github.com/Kiratopat-s/workflow/internal/signingkey"
+	"github.com/Kiratopat-s/workflow/internal/sso"
+	"github.com/Kiratopat-s/workflow/internal/token"
+	"github.com/Kiratopat-s/workflow/internal/twofactor"
 	"github.com/Kiratopat-s/workflow/internal/user"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -27,19 +44,97 @@ func main() {
 	}
 
 	// Controller
-	controller := item.NewController(db)
-	userController := user.NewController(db, "secret")
+	approvalController := approval.NewController(db)
+	delegationController := delegation.NewController(db)
+	ruleController := rule.NewController(db)
+	roleController := role.NewController(db)
+	tokenController := token.NewController(db)
+	sessionController := session.NewController(db)
+	apiKeyController := apikey.NewController(db)
 
-	// verifyToken middleware
+	// Outgoing mail
+	mailer, err := mail.SenderFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Login lockouts
+	lockoutConfig, err := lockout.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	lockoutController := lockout.NewController(db, lockoutConfig)
+	twoFactorController := twofactor.NewController(db, lockoutController.Service)
+
+	// Items and their comments, approved only by users meeting the 2FA requirement
+	controller := item.NewController(db, twoFactorController.Service)
+	commentController := comment.NewController(db, twoFactorController.Service)
+
+	// Password hashing and policy
+	passwordConfig, err := password.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	userController := user.NewController(db, "secret", mailer, lockoutController.Service, passwordConfig)
+
+	// Single sign-on
+	ssoConfig, err := sso.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	ssoController := sso.NewController(db, ssoConfig, lockoutController.Service)
+
+	// Token signing keys
+	algorithm, err := auth.Algorithm()
+	if err != nil {
+		log.Fatal(err)
+	}
 	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
+	if secret == "" && algorithm == auth.AlgorithmHS256 {
 		log.Fatal("JWT_SECRET is not set")
 	}
-	verifyToken := auth.Guard(secret)
-	verifyAdmin := auth.GuardAdmin(secret)
+	signingKeyController := signingkey.NewController(db, algorithm)
+	if err := signingKeyController.Service.Ensure(); err != nil {
+		log.Fatal(err)
+	}
+	keys, err := auth.NewKeySet(signingKeyController.Service.Load)
+	if err != nil {
+		log.Fatal(err)
+	}
+	signingKeyController.Keys = keys
+	if algorithm != auth.AlgorithmHS256 {
+		auth.UseKeySet(keys)
+	}
+
+	// verifyToken middleware, verifyTokenOrKey also accepts API keys
+	authConfig := auth.Config{
+		Secret:      secret,
+		Keys:        keys,
+		Extractors:  []auth.TokenExtractor{auth.FromHeader(), auth.FromCookie("token")},
+		Permissions: twoFactorController.Service.EnforcePermissions(roleController.Service.PermissionsFor),
+		Revocations: tokenController.Service.IsRevoked,
+		Sessions:    sessionController.Service.IsActive,
+		Accounts:    userController.Service.IsActive,
+	}
+	verifyToken := auth.Guard(authConfig)
+	keyConfig := authConfig
+	keyConfig.APIKeys = apiKeyController.Service.Authenticate
+	verifyTokenOrKey := auth.Guard(keyConfig)
+	readItems := auth.RequireScope(constant.ItemsReadScope)
+	writeItems := auth.RequireScope(constant.ItemsWriteScope)
+	requireVerifiedEmail := auth.RequireVerifiedEmail(userController.Service.IsEmailVerified)
+	requireApprover := auth.RequireApprover(delegationController.Service.ActiveApproverDelegator)
 
 	// Router setup
 	r := gin.Default()
+	// Login lockouts count client addresses; only trusted proxies may set them
+	var trustedProxies []string
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		trustedProxies = strings.Split(value, ",")
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal(err)
+	}
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{
 		"http://localhost:8000",
@@ -64,28 +159,117 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"message": "Hello, World!"})
 	})
 	r.GET("/hello-verifytoken", verifyToken, func(c *gin.Context) {
+		principal := auth.MustPrincipal(c)
 		c.JSON(http.StatusOK, gin.H{
-			"message":   "Hello, World!",
-			"uid":       c.MustGet("uid"),
-			"username":  c.MustGet("username"),
-			"firstName": c.MustGet("firstName"),
-			"lastName":  c.MustGet("lastName"),
-			"position":  c.MustGet("position"),
-			"photoLink": c.MustGet("photoLink"),
+			"message":     "Hello, World!",
+			"uid":         principal.UID,
+			"username":    principal.Username,
+			"firstName":   principal.FirstName,
+			"lastName":    principal.LastName,
+			"position":    principal.Position,
+			"photoLink":   principal.PhotoLink,
+			"permissions": principal.Permissions,
 		})
 	})
-	r.POST("/items", verifyToken, controller.CreateItem)
-	r.GET("/items", verifyToken, controller.FindAllItem)
-	r.GET("/items/:id", verifyToken, controller.FindItemByID)
-	r.PUT("/items/:id", verifyToken, controller.UpdateItem)
-	r.PATCH("/items/:id", verifyToken, controller.UpdateItemStatus)
-	r.PATCH("/items/update/status/many", verifyAdmin, controller.UpdateManyItemsStatus)
-	r.DELETE("/items/:id", verifyToken, controller.DeleteItem)
-	r.DELETE("/items/delete/many", verifyToken, controller.DeleteManyItems)
-	r.GET("/items/status/count/user", verifyToken, controller.CountItemsStatusByUser)
+	r.POST("/items", verifyTokenOrKey, writeItems, auth.RequirePermission(constant.ItemsCreatePermission), requireVerifiedEmail, controller.CreateItem)
+	r.GET("/items", verifyTokenOrKey, readItems, controller.FindAllItem)
+	r.GET("/items/:id", verifyTokenOrKey, readItems, controller.FindItemByID)
+	r.PUT("/items/:id", verifyTokenOrKey, writeItems, controller.UpdateItem)
+	r.PUT("/items/:id/override", verifyToken, auth.RequirePermission(constant.ItemsOverridePermission), controller.OverrideItem)
+	r.PATCH("/items/:id", verifyTokenOrKey, writeItems, controller.UpdateItemStatus)
+	r.PATCH("/items/update/status/many", verifyTokenOrKey, writeItems, requireApprover, controller.UpdateManyItemsStatus)
+	r.DELETE("/items/:id", verifyTokenOrKey, writeItems, controller.DeleteItem)
+	r.DELETE("/items/delete/many", verifyTokenOrKey, writeItems, controller.DeleteManyItems)
+	r.GET("/items/status/count/user", verifyTokenOrKey, readItems, controller.CountItemsStatusByUser)
+	r.GET("/items/:id/approvals", verifyTokenOrKey, readItems, controller.FindItemApprovalSteps)
+	r.GET("/items/:id/history", verifyTokenOrKey, readItems, controller.FindItemHistory)
+	r.POST("/items/:id/resubmit", verifyTokenOrKey, writeItems, controller.ResubmitItem)
+	r.GET("/items/:id/revisions", verifyTokenOrKey, readItems, controller.FindItemRevisions)
+	r.GET("/items/:id/revisions/diff", verifyTokenOrKey, readItems, controller.DiffItemRevisions)
+	r.POST("/items/:id/comments", verifyTokenOrKey, writeItems, commentController.CreateComment)
+	r.GET("/items/:id/comments", verifyTokenOrKey, readItems, commentController.FindComments)
+	r.PATCH("/items/:id/comments/:commentId", verifyTokenOrKey, writeItems, commentController.UpdateComment)
+	r.DELETE("/items/:id/comments/:commentId", verifyTokenOrKey, writeItems, commentController.DeleteComment)
+	r.GET("/approval-levels", verifyToken, approvalController.FindLevels)
+	r.PUT("/approval-levels", verifyToken, auth.RequirePermission(constant.ApprovalLevelsManagePermission), approvalController.ReplaceLevels)
+	r.POST("/delegations", verifyToken, delegationController.CreateDelegation)
+	r.GET("/delegations", verifyToken, delegationController.FindDelegations)
+	r.DELETE("/delegations/:id", verifyToken, delegationController.RevokeDelegation)
+	manageRules := auth.RequirePermission(constant.RulesManagePermission)
+	r.GET("/rules", verifyToken, manageRules, ruleController.FindAllRules)
+	r.POST("/rules", verifyToken, manageRules, ruleController.CreateRule)
+	r.POST("/rules/dry-run", verifyToken, manageRules, ruleController.DryRunRule)
+	r.GET("/rules/:id", verifyToken, manageRules, ruleController.FindRuleByID)
+	r.PUT("/rules/:id", verifyToken, manageRules, ruleController.UpdateRule)
+	r.DELETE("/rules/:id", verifyToken, manageRules, ruleController.DeleteRule)
+	manageRoles := auth.RequirePermission(constant.RolesManagePermission)
+	r.GET("/roles", verifyToken, manageRoles, roleController.FindRoles)
+	r.GET("/permissions", verifyToken, manageRoles, roleController.FindPermissions)
+	manageUsers := auth.RequirePermission(constant.UsersManagePermission)
+	r.GET("/users", verifyToken, manageUsers, userController.FindUsers)
+	r.GET("/users/:id", verifyToken, manageUsers, userController.FindUser)
+	r.PATCH("/users/:id", verifyToken, manageUsers, userController.UpdateUser)
+	r.POST("/users/:id/deactivate", verifyToken, manageUsers, userController.DeactivateUser)
+	r.POST("/users/:id/reactivate", verifyToken, manageUsers, userController.ReactivateUser)
+	r.POST("/users/:id/password-reset", verifyToken, manageUsers, userController.ForcePasswordReset)
+	manageInvitations := auth.RequirePermission(constant.InvitationsManagePermission)
+	r.POST("/invitations", verifyToken, manageInvitations, userController.CreateInvitation)
+	r.GET("/invitations", verifyToken, manageInvitations, userController.FindInvitations)
+	r.DELETE("/invitations/:id", verifyToken, manageInvitations, userController.RevokeInvitation)
+	r.GET("/users/:id/roles", verifyToken, manageRoles, roleController.FindUserRoles)
+	r.PUT("/users/:id/roles", verifyToken, manageRoles, roleController.SetUserRoles)
 	r.POST("/login", userController.Login)
 	r.POST("/register", userController.Register)
-	// r.POST("/logout", verifyToken, userController.Logout)
+	r.POST("/login/2fa", twoFactorController.LoginTwoFactor)
+	if ssoConfig.Enabled() {
+		r.GET("/auth/sso/login", ssoController.Login)
+		r.GET("/auth/sso/callback", ssoController.Callback)
+	}
+	r.POST("/token/refresh", tokenController.Refresh)
+	r.POST("/logout", verifyToken, tokenController.Logout)
+	r.GET("/me", verifyToken, userController.FindProfile)
+	r.PATCH("/me", verifyToken, userController.UpdateProfile)
+	r.PUT("/me/password", verifyToken, userController.ChangePassword)
+	r.GET("/me/sessions", verifyToken, sessionController.FindMySessions)
+	r.DELETE("/me/sessions", verifyToken, sessionController.RevokeMyOtherSessions)
+	r.DELETE("/me/sessions/:id", verifyToken, sessionController.RevokeMySession)
+	manageSessions := auth.RequirePermission(constant.SessionsManagePermission)
+	r.GET("/users/:id/sessions", verifyToken, manageSessions, sessionController.FindUserSessions)
+	r.DELETE("/users/:id/sessions", verifyToken, manageSessions, sessionController.RevokeUserSessions)
+	r.DELETE("/users/:id/sessions/:session_id", verifyToken, manageSessions, sessionController.RevokeUserSession)
+	r.POST("/password/forgot", userController.ForgotPassword)
+	r.POST("/password/reset", userController.ResetPassword)
+	r.POST("/email/verify", userController.VerifyEmail)
+	r.POST("/me/email/verification", verifyToken, userController.ResendVerification)
+	r.GET("/me/2fa", verifyToken, twoFactorController.FindStatus)
+	r.POST("/me/2fa/enroll", verifyToken, twoFactorController.Enroll)
+	r.POST("/me/2fa/confirm", verifyToken, twoFactorController.Confirm)
+	r.POST("/me/2fa/recovery-codes", verifyToken, twoFactorController.RegenerateRecoveryCodes)
+	r.DELETE("/me/2fa", verifyToken, twoFactorController.Disable)
+	r.GET("/settings/2fa", verifyToken, auth.RequirePermission(constant.SettingsManagePermission), twoFactorController.FindSettings)
+	r.PUT("/settings/2fa", verifyToken, auth.RequirePermission(constant.SettingsManagePermission), twoFactorController.UpdateSettings)
+	r.POST("/api-keys", verifyToken, apiKeyController.CreateAPIKey)
+	r.GET("/api-keys", verifyToken, apiKeyController.FindAPIKeys)
+	r.DELETE("/api-keys/:id", verifyToken, apiKeyController.RevokeAPIKey)
+	r.GET("/.well-known/jwks.json", signingKeyController.JWKS)
+	r.GET("/keys", verifyToken, auth.RequirePermission(constant.KeysManagePermission), signingKeyController.FindKeys)
+	r.POST("/keys/rotate", verifyToken, auth.RequirePermission(constant.KeysManagePermission), signingKeyController.RotateKey)
+	r.POST("/tokens/revoke", verifyToken, auth.RequirePermission(constant.TokensRevokePermission), tokenController.RevokeToken)
+	r.GET("/lockouts", verifyToken, auth.RequirePermission(constant.LockoutsManagePermission), lockoutController.FindLockouts)
+	r.DELETE("/lockouts/:id", verifyToken, auth.RequirePermission(constant.LockoutsManagePermission), lockoutController.ClearLockout)
+
+	// Escalation worker
+	escalationConfig, err := escalation.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	worker := escalation.NewWorker(db, escalationConfig, escalation.LogNotifier{})
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		worker.Run(workerCtx)
+	}()
 
 	// Graceful shutdown setup
 	srv := &http.Server{
@@ -113,6 +297,14 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	// Stop the escalation worker
+	stopWorker()
+	select {
+	case <-workerDone:
+	case <-ctx.Done():
+		log.Println("Escalation worker did not stop in time")
+	}
+
 	log.Println("Server exiting")
 }
 
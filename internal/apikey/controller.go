@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB) Controller {
+	return Controller{
+		Service: NewService(db),
+	}
+}
+
+func (controller Controller) CreateAPIKey(ctx *gin.Context) {
+	// Bind
+	var request model.RequestCreateAPIKey
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	apiKey, err := controller.Service.Create(request, uid)
+	if err != nil {
+		if errors.Is(err, ErrInvalidExpiry) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data": apiKey,
+	})
+}
+
+func (controller Controller) FindAPIKeys(ctx *gin.Context) {
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	apiKeys, err := controller.Service.FindByUser(uid)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": apiKeys,
+	})
+}
+
+func (controller Controller) RevokeAPIKey(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	if err := controller.Service.Revoke(uint(id), uid); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, ErrNotOwner):
+			// Other users' keys are reported as missing
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"message": "API key not found",
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked",
+	})
+}
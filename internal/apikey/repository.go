@@ -0,0 +1,71 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) Create(key *model.APIKey) error {
+	return repo.Database.Create(key).Error
+}
+
+func (repo Repository) FindByUser(uid int) ([]model.APIKey, error) {
+	var results []model.APIKey
+	if err := repo.Database.Where("user_id = ?", uid).Order("id DESC").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) FindByID(id uint) (model.APIKey, error) {
+	var result model.APIKey
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+func (repo Repository) FindByHash(hash string) (model.APIKey, error) {
+	var result model.APIKey
+	err := repo.Database.Where("key_hash = ?", hash).First(&result).Error
+	return result, err
+}
+
+func (repo Repository) FindUser(id uint) (model.User, error) {
+	var result model.User
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+func (repo Repository) Revoke(id uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+// RevokeByUser revokes every active key of a user.
+func (repo Repository) RevokeByUser(uid uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", uid).
+		Update("revoked_at", at).Error
+}
+
+func (repo Repository) Touch(id uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+}
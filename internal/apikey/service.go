@@ -0,0 +1,128 @@
+package apikey
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// keyPrefix marks API keys so they are easy to spot, e.g. by secret scanners.
+	keyPrefix = "wfk_"
+	// defaultTTL is the lifetime of keys created without an expiry.
+	defaultTTL = 90 * 24 * time.Hour
+	// maxTTL is the longest lifetime a key may have.
+	maxTTL = 365 * 24 * time.Hour
+	// touchInterval limits how often last_used_at is written for a busy key.
+	touchInterval = time.Minute
+)
+
+var (
+	ErrInvalidExpiry = errors.New("expires_at must be in the future and at most one year away")
+	ErrNotOwner      = errors.New("only the owner can revoke this API key")
+)
+
+type Service struct {
+	Repository Repository
+}
+
+func NewService(db *gorm.DB) Service {
+	return Service{
+		Repository: NewRepository(db),
+	}
+}
+
+// Create issues an API key for uid. The returned key is shown once; only
+// its hash is stored.
+func (service Service) Create(req model.RequestCreateAPIKey, uid int) (model.ResponseCreateAPIKey, error) {
+	now := time.Now()
+	expiresAt := now.Add(defaultTTL)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > maxTTL {
+		return model.ResponseCreateAPIKey{}, ErrInvalidExpiry
+	}
+
+	secret, err := auth.NewOpaqueToken()
+	if err != nil {
+		return model.ResponseCreateAPIKey{}, err
+	}
+	key := keyPrefix + secret
+
+	apiKey := model.APIKey{
+		UserID:    uint(uid),
+		Name:      req.Name,
+		Prefix:    key[:len(keyPrefix)+8],
+		KeyHash:   auth.HashToken(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := service.Repository.Create(&apiKey); err != nil {
+		return model.ResponseCreateAPIKey{}, err
+	}
+	return model.ResponseCreateAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+func (service Service) FindByUser(uid int) ([]model.APIKey, error) {
+	return service.Repository.FindByUser(uid)
+}
+
+func (service Service) Revoke(id uint, uid int) error {
+	apiKey, err := service.Repository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if apiKey.UserID != uint(uid) {
+		return ErrNotOwner
+	}
+	return service.Repository.Revoke(id, time.Now())
+}
+
+// Authenticate returns the owner of key as a principal limited to the key's
+// scopes. It is the API key lookup of auth.Guard.
+func (service Service) Authenticate(key string) (auth.Principal, error) {
+	apiKey, err := service.Repository.FindByHash(auth.HashToken(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || !apiKey.ExpiresAt.After(now) {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+
+	user, err := service.Repository.FindUser(apiKey.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > touchInterval {
+		if err := service.Repository.Touch(apiKey.ID, now); err != nil {
+			log.Printf("Recording API key use failed: %v\n", err)
+		}
+	}
+
+	return auth.Principal{
+		UID:       int(user.ID),
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Position:  user.Position,
+		PhotoLink: user.PhotoLink,
+		ExpiresAt: apiKey.ExpiresAt,
+		APIKeyID:  &apiKey.ID,
+		Scopes:    apiKey.Scopes,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package approval
+
+import (
+	"net/http"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB) Controller {
+	return Controller{
+		Service: NewService(db),
+	}
+}
+
+func (controller Controller) FindLevels(ctx *gin.Context) {
+	levels, err := controller.Service.Levels()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": levels,
+	})
+}
+
+func (controller Controller) ReplaceLevels(ctx *gin.Context) {
+	// Bind
+	var request model.RequestPutApprovalLevels
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	levels, err := controller.Service.ReplaceLevels(request)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": levels,
+	})
+}
@@ -0,0 +1,81 @@
+package approval
+
+import (
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) FindLevels() ([]model.ApprovalLevel, error) {
+	var results []model.ApprovalLevel
+	if err := repo.Database.Order("level").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) ReplaceLevels(levels []model.ApprovalLevel) error {
+	return repo.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.ApprovalLevel{}).Error; err != nil {
+			return err
+		}
+		if len(levels) == 0 {
+			return nil
+		}
+		return tx.Create(&levels).Error
+	})
+}
+
+func (repo Repository) FindStepsByItemID(itemID uint) ([]model.ApprovalStep, error) {
+	var results []model.ApprovalStep
+	if err := repo.Database.Where("item_id = ?", itemID).Order("round, level").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// FindPendingStepsByItemIDs returns the open steps of several items, in the
+// order each item's steps are decided in.
+func (repo Repository) FindPendingStepsByItemIDs(itemIDs []uint) ([]model.ApprovalStep, error) {
+	var results []model.ApprovalStep
+	err := repo.Database.
+		Where("item_id IN ? AND status = ?", itemIDs, constant.ApprovalStepPendingStatus).
+		Order("item_id, round, level").
+		Find(&results).Error
+	return results, err
+}
+
+func (repo Repository) LatestRound(itemID uint) (int, error) {
+	var round int
+	err := repo.Database.Model(&model.ApprovalStep{}).Select("COALESCE(MAX(round), 0)").Where("item_id = ?", itemID).Scan(&round).Error
+	return round, err
+}
+
+func (repo Repository) CreateSteps(steps []model.ApprovalStep) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	return repo.Database.Create(&steps).Error
+}
+
+func (repo Repository) UpdateStep(step model.ApprovalStep) error {
+	return repo.Database.Save(&step).Error
+}
+
+// CancelPendingSteps closes every open step of an item.
+func (repo Repository) CancelPendingSteps(itemID uint) error {
+	return repo.Database.Model(&model.ApprovalStep{}).
+		Where("item_id = ? AND status = ?", itemID, constant.ApprovalStepPendingStatus).
+		Update("status", constant.ApprovalStepCancelledStatus).Error
+}
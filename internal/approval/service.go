@@ -0,0 +1,228 @@
+package approval
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Outcome is the state of an item's approval chain after a decision.
+type Outcome int
+
+const (
+	OutcomeInProgress Outcome = iota
+	OutcomeApproved
+	OutcomeRejected
+)
+
+var ErrNotApprover = errors.New("you are not the approver of the current approval step")
+
+type Service struct {
+	Repository Repository
+}
+
+func NewService(db *gorm.DB) Service {
+	return Service{
+		Repository: NewRepository(db),
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	return service
+}
+
+func (service Service) Levels() ([]model.ApprovalLevel, error) {
+	return service.Repository.FindLevels()
+}
+
+func (service Service) ReplaceLevels(req model.RequestPutApprovalLevels) ([]model.ApprovalLevel, error) {
+	levels := make([]model.ApprovalLevel, len(req.Levels))
+	for i, l := range req.Levels {
+		levels[i] = model.ApprovalLevel{
+			Level:            l.Level,
+			Name:             l.Name,
+			ApproverPosition: l.ApproverPosition,
+			MinTotal:         l.MinTotal,
+		}
+	}
+
+	if err := service.Repository.ReplaceLevels(levels); err != nil {
+		return nil, err
+	}
+	return service.Repository.FindLevels()
+}
+
+func (service Service) Steps(itemID uint) ([]model.ApprovalStep, error) {
+	return service.Repository.FindStepsByItemID(itemID)
+}
+
+// Start opens a new approval round for the item with one step for every
+// level whose threshold the item total exceeds. Open steps of earlier rounds
+// are cancelled.
+func (service Service) Start(item model.Item) ([]model.ApprovalStep, error) {
+	if err := service.Repository.CancelPendingSteps(item.ID); err != nil {
+		return nil, err
+	}
+
+	levels, err := service.Repository.FindLevels()
+	if err != nil {
+		return nil, err
+	}
+	round, err := service.Repository.LatestRound(item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []model.ApprovalStep{}
+	for _, level := range levels {
+		if item.Total() <= level.MinTotal {
+			continue
+		}
+		steps = append(steps, model.ApprovalStep{
+			ItemID:           item.ID,
+			Round:            round + 1,
+			Level:            level.Level,
+			Name:             level.Name,
+			ApproverPosition: level.ApproverPosition,
+			Status:           constant.ApprovalStepPendingStatus,
+		})
+	}
+
+	if err := service.Repository.CreateSteps(steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// Route makes the item's current round go through an approver of the given
+// position first, unless the round already has a step for it.
+func (service Service) Route(item model.Item, position string, name string) error {
+	round, err := service.Repository.LatestRound(item.ID)
+	if err != nil {
+		return err
+	}
+	steps, err := service.Repository.FindStepsByItemID(item.ID)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if step.Round == round && step.ApproverPosition == position && step.Status != constant.ApprovalStepCancelledStatus {
+			return nil
+		}
+	}
+
+	return service.Repository.CreateSteps([]model.ApprovalStep{{
+		ItemID:           item.ID,
+		Round:            round,
+		Level:            0,
+		Name:             name,
+		ApproverPosition: position,
+		Status:           constant.ApprovalStepPendingStatus,
+	}})
+}
+
+// Cancel closes the open steps of an item that left the approval process.
+func (service Service) Cancel(itemID uint) error {
+	return service.Repository.CancelPendingSteps(itemID)
+}
+
+// CurrentStep returns the first open step of the item's latest round. It
+// only reads: rounds are opened by Start when the item is submitted.
+func (service Service) CurrentStep(item model.Item) (model.ApprovalStep, bool, error) {
+	steps, err := service.Repository.FindStepsByItemID(item.ID)
+	if err != nil {
+		return model.ApprovalStep{}, false, err
+	}
+	for _, step := range steps {
+		if step.Status == constant.ApprovalStepPendingStatus {
+			return step, true, nil
+		}
+	}
+	return model.ApprovalStep{}, false, nil
+}
+
+// CurrentSteps is CurrentStep for several items at once, keyed by item ID.
+// Items without an open step are left out.
+func (service Service) CurrentSteps(itemIDs []uint) (map[uint]model.ApprovalStep, error) {
+	current := map[uint]model.ApprovalStep{}
+	if len(itemIDs) == 0 {
+		return current, nil
+	}
+	steps, err := service.Repository.FindPendingStepsByItemIDs(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if _, ok := current[step.ItemID]; !ok {
+			current[step.ItemID] = step
+		}
+	}
+	return current, nil
+}
+
+// Decider is a user deciding an approval step.
+type Decider struct {
+	ID int
+	// Position is the one the step is decided with, which is the
+	// delegator's when OnBehalfOf is set
+	Position string
+	// ApproveAny is set for users allowed to decide any step
+	ApproveAny bool
+	// OnBehalfOf is the delegator when deciding under a delegation
+	OnBehalfOf *int
+}
+
+// CanDecide reports whether the decider may decide step.
+func (decider Decider) CanDecide(step model.ApprovalStep) bool {
+	return decider.ApproveAny || decider.Position == step.ApproverPosition
+}
+
+// Decide records an approval or rejection on the item's current step and
+// returns the decided step. A rejection ends the chain; an approval of the
+// last step completes it. Items without any step need a single decision.
+func (service Service) Decide(item model.Item, approve bool, decider Decider) (Outcome, model.ApprovalStep, error) {
+	step, ok, err := service.CurrentStep(item)
+	if err != nil {
+		return OutcomeInProgress, step, err
+	}
+	if !ok {
+		if approve {
+			return OutcomeApproved, step, nil
+		}
+		return OutcomeRejected, step, nil
+	}
+
+	if !decider.CanDecide(step) {
+		return OutcomeInProgress, step, ErrNotApprover
+	}
+
+	now := time.Now()
+	step.DecidedBy = &decider.ID
+	step.DecidedOnBehalfOf = decider.OnBehalfOf
+	step.DecidedAt = &now
+	step.Status = constant.ApprovalStepApprovedStatus
+	if !approve {
+		step.Status = constant.ApprovalStepRejectedStatus
+	}
+	if err := service.Repository.UpdateStep(step); err != nil {
+		return OutcomeInProgress, step, err
+	}
+
+	if !approve {
+		if err := service.Repository.CancelPendingSteps(item.ID); err != nil {
+			return OutcomeInProgress, step, err
+		}
+		return OutcomeRejected, step, nil
+	}
+
+	if _, ok, err := service.CurrentStep(item); err != nil || ok {
+		return OutcomeInProgress, step, err
+	}
+	return OutcomeApproved, step, nil
+}
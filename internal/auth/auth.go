@@ -1,7 +1,10 @@
 package auth
 
 import (
-	"fmt"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"log"
 	"os"
 	"time"
@@ -9,28 +12,74 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func CreateToken(uid uint, username string, firstName string, lastName string,position string,photoLink string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET is not set")
+func CreateToken(uid uint, username string, firstName string, lastName string,position string,photoLink string, sessionID uint) (string, error) {
+	jti, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
 	}
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	claims := jwt.MapClaims{
+		"jti":      jti,
 		"uid":      uid,
 		"username": username,
 		"firstName": firstName,
 		"lastName": lastName,
 		"position": position,
 		"photoLink": photoLink,
-		"exp":      time.Now().Add(time.Minute*30).Unix(),
-	})
+		"sid":      sessionID,
+		"iat":      time.Now().Unix(),
+		"exp":      time.Now().Add(AccessTokenTTL()).Unix(),
+	}
+
+	// Sign with the active asymmetric key, which verifiers find by kid
+	if keys := currentKeySet(); keys != nil {
+		key, ok := keys.SigningKey()
+		if !ok {
+			return "", ErrNoSigningKey
+		}
+		t := jwt.NewWithClaims(key.method(), claims)
+		t.Header["kid"] = key.ID
+		return t.SignedString(key.Private)
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Fatal("JWT_SECRET is not set")
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	signedToken, err := t.SignedString([]byte(secret))
 	if err != nil {
 		log.Println("error signing key")
 		return signedToken, err
 	}
-	fmt.Print("Token || ", signedToken)
 	return signedToken, nil
 }
 
+// AccessTokenTTL is how long access tokens live, read from ACCESS_TOKEN_TTL
+// (a Go duration, 30 minutes by default).
+func AccessTokenTTL() time.Duration {
+	if value := os.Getenv("ACCESS_TOKEN_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("Invalid ACCESS_TOKEN_TTL %q, using the default\n", value)
+	}
+	return 30 * time.Minute
+}
 
+// NewOpaqueToken returns a random URL-safe token with 256 bits of entropy.
+func NewOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of an opaque token, which is
+// what gets stored in the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
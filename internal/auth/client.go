@@ -0,0 +1,22 @@
+package auth
+
+import "github.com/gin-gonic/gin"
+
+// maxUserAgentLength bounds the user agents stored with sessions.
+const maxUserAgentLength = 512
+
+// Client is where a request comes from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// ClientFrom returns the client of a request. The IP address is only taken
+// from X-Forwarded-For behind the router's trusted proxies.
+func ClientFrom(c *gin.Context) Client {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return Client{IP: c.ClientIP(), UserAgent: userAgent}
+}
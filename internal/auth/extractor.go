@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenExtractor finds the raw token in a request. It returns false when the
+// request does not carry a token in the place it looks at.
+type TokenExtractor func(c *gin.Context) (string, bool)
+
+// FromHeader reads "Authorization: Bearer <token>".
+func FromHeader() TokenExtractor {
+	return func(c *gin.Context) (string, bool) {
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		token = strings.TrimSpace(token)
+		return token, token != ""
+	}
+}
+
+// FromCookie reads the token from the named cookie. The value may carry the
+// "Bearer " prefix returned by /login.
+func FromCookie(name string) TokenExtractor {
+	return func(c *gin.Context) (string, bool) {
+		value, err := c.Cookie(name)
+		if err != nil {
+			return "", false
+		}
+		token := strings.TrimSpace(strings.TrimPrefix(value, "Bearer "))
+		return token, token != ""
+	}
+}
+
+// FromQuery reads the token from the named query parameter. It is meant for
+// clients that cannot set headers, like EventSource for server-sent events;
+// query strings end up in access logs, so only use it on such routes.
+func FromQuery(name string) TokenExtractor {
+	return func(c *gin.Context) (string, bool) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.Query(name), "Bearer "))
+		return token, token != ""
+	}
+}
@@ -1,132 +1,324 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
 
+	"github.com/Kiratopat-s/workflow/internal/constant"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func Guard(secret string) gin.HandlerFunc {
+// PermissionLookup returns the permissions a user currently holds.
+type PermissionLookup func(uid int) ([]string, error)
+
+// RevocationLookup reports whether the access token with the given jti was revoked.
+type RevocationLookup func(jti string) (bool, error)
+
+// SessionLookup reports whether the session an access token was issued
+// for is still active.
+type SessionLookup func(sessionID uint) (bool, error)
+
+// AccountLookup reports whether a user's account is active.
+type AccountLookup func(uid int) (bool, error)
+
+// APIKeyLookup authenticates an API key, returning its owner with the key's
+// scopes. It returns ErrInvalidAPIKey for unknown, expired or revoked keys.
+type APIKeyLookup func(key string) (Principal, error)
+
+// ErrInvalidAPIKey is returned by an APIKeyLookup for keys that cannot be used.
+var ErrInvalidAPIKey = errors.New("API key is invalid, expired or revoked")
+
+// APIKeyHeader is the header API keys are sent in.
+const APIKeyHeader = "X-API-Key"
+
+// Config configures Guard.
+type Config struct {
+	// Secret verifies HS256 tokens, which carry no kid.
+	Secret string
+	// Keys verifies tokens signed with an asymmetric key, found by kid.
+	Keys *KeySet
+	// Extractors are tried in order; the first one that finds a token wins.
+	Extractors []TokenExtractor
+	// Permissions loads the caller's permissions into the principal.
+	Permissions PermissionLookup
+	// Revocations rejects access tokens revoked before they expire.
+	Revocations RevocationLookup
+	// Sessions rejects access tokens whose session was signed out.
+	Sessions SessionLookup
+	// Accounts rejects callers whose account was deactivated.
+	Accounts AccountLookup
+	// APIKeys, when set, also accepts API keys sent in the X-API-Key header.
+	APIKeys APIKeyLookup
+}
+
+// Guard authenticates the request: it extracts the token, verifies it,
+// rejects revoked tokens and stores the caller as a Principal in the context.
+func Guard(config Config) gin.HandlerFunc {
+	if len(config.Extractors) == 0 {
+		config.Extractors = []TokenExtractor{FromHeader(), FromCookie("token")}
+	}
+
 	return func(c *gin.Context) {
-		// Extract token "Bearer xxx" from cookie
-		auth, err := c.Cookie("token")
-		if err != nil {
-			log.Println("Token missing in cookie")
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
+		var (
+			principal Principal
+			err       error
+		)
+		if key := c.GetHeader(APIKeyHeader); key != "" && config.APIKeys != nil {
+			principal, err = config.APIKeys(key)
+			if err != nil {
+				log.Printf("API key authentication failed: %v\n", err)
+				status := http.StatusUnauthorized
+				if !errors.Is(err, ErrInvalidAPIKey) {
+					status = http.StatusInternalServerError
+				}
+				c.AbortWithStatus(status)
+				return
+			}
+		} else {
+			tokenString, ok := extractToken(c, config.Extractors)
+			if !ok {
+				log.Println("Token missing")
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+
+			token, err := verifyToken(tokenString, config.Secret, config.Keys)
+			if err != nil {
+				log.Printf("Token verification failed: %v\n", err)
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+
+			principal, err = principalFromClaims(token)
+			if err != nil {
+				log.Printf("Token claims are invalid: %v\n", err)
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
 		}
 
-		// Remove prefix "Bearer " from auth token
-		tokenString := strings.TrimPrefix(auth, "Bearer ")
-		if tokenString == auth {
-			log.Println("Token does not have the expected 'Bearer ' prefix")
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
+		if config.Revocations != nil && principal.JTI != "" {
+			revoked, err := config.Revocations(principal.JTI)
+			if err != nil {
+				log.Printf("Revocation lookup failed: %v\n", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				log.Println("Token was revoked")
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
 		}
 
-		token, err := verifyToken(tokenString, secret)
-		if err != nil {
-			log.Printf("Token verification failed: %v\n", err)
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
+		if config.Sessions != nil && principal.SessionID != 0 {
+			active, err := config.Sessions(principal.SessionID)
+			if err != nil {
+				log.Printf("Session lookup failed: %v\n", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				log.Println("Session was signed out")
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			log.Printf("Token verified successfully. Claims: %+v\n", claims)
-			
-			keys := []string{"uid", "username", "firstName", "lastName", "position", "photoLink"}
+		if config.Accounts != nil {
+			active, err := config.Accounts(principal.UID)
+			if err != nil {
+				log.Printf("Account lookup failed: %v\n", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				log.Println("Account is deactivated")
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
 
-			for _, key := range keys {
-				if value, ok := claims[key].(string); ok {
-					c.Set(key, value)
-				} else if key == "uid" {
-					if value, ok := claims[key].(float64); ok {
-						c.Set(key, value)
-					}
-				}
+		if config.Permissions != nil {
+			permissions, err := config.Permissions(principal.UID)
+			if err != nil {
+				log.Printf("Permission lookup failed: %v\n", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
 			}
-		} else {
-			log.Println("Token claims are invalid or token is not valid")
-			c.AbortWithStatus(http.StatusUnauthorized)
+			principal.Permissions = principal.scopedPermissions(permissions)
 		}
+
+		setPrincipal(c, principal)
 	}
 }
 
-func verifyToken(tokenString string, secret string) (*jwt.Token, error) {
-	// Parse the token with the secret key
+func extractToken(c *gin.Context, extractors []TokenExtractor) (string, bool) {
+	for _, extract := range extractors {
+		if token, ok := extract(c); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+func verifyToken(tokenString string, secret string, keys *KeySet) (*jwt.Token, error) {
+	// Parse the token with the key it names, or the secret key
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if kid, ok := token.Header["kid"].(string); ok {
+			if keys == nil {
+				return nil, fmt.Errorf("unknown key %q", kid)
+			}
+			key, ok := keys.VerificationKey(kid)
+			if !ok {
+				return nil, fmt.Errorf("unknown key %q", kid)
+			}
+			// The algorithm must be the key's, never the one the token claims
+			if token.Method.Alg() != key.method().Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return key.Public, nil
+		}
+
 		// Validate that the algorithm matches HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || secret == "" {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		// Return the secret key
 		return []byte(secret), nil
 	})
-	
 
 	// Check for verification errors
 	if err != nil {
 		return nil, fmt.Errorf("token parsing failed: %v", err)
 	}
-
-	log.Println("Token parsed successfully, checking signature validity")
+	if !token.Valid {
+		return nil, errors.New("token is not valid")
+	}
 
 	// Return the verified token
 	return token, nil
 }
 
-func GuardAdmin(secret string) gin.HandlerFunc {
+// principalFromClaims builds the principal from the claims set by CreateToken.
+func principalFromClaims(token *jwt.Token) (Principal, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Principal{}, errors.New("unexpected claims type")
+	}
+
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return Principal{}, errors.New("token has no uid")
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return Principal{}, errors.New("token has no jti")
+	}
+
+	principal := Principal{UID: int(uid), JTI: jti}
+	principal.Username, _ = claims["username"].(string)
+	principal.FirstName, _ = claims["firstName"].(string)
+	principal.LastName, _ = claims["lastName"].(string)
+	principal.Position, _ = claims["position"].(string)
+	principal.PhotoLink, _ = claims["photoLink"].(string)
+	// Tokens issued before sessions were recorded carry no sid
+	if sid, ok := claims["sid"].(float64); ok {
+		principal.SessionID = uint(sid)
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		principal.ExpiresAt = exp.Time
+	}
+	return principal, nil
+}
+
+// HasPermission reports whether the caller, authenticated by Guard, holds permission.
+func HasPermission(c *gin.Context, permission constant.Permission) bool {
+	principal, ok := PrincipalFrom(c)
+	return ok && principal.Can(permission)
+}
+
+// RequirePermission lets through callers holding permission. It must run after Guard.
+func RequirePermission(permission constant.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Extract token "Bearer xxx" from cookie
-		auth, err := c.Cookie("token")
-		if err != nil {
-			log.Println("Token missing in cookie")
-			c.AbortWithStatus(http.StatusUnauthorized)
+		if !HasPermission(c, permission) {
+			log.Printf("User lacks permission %s\n", permission)
+			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
+	}
+}
 
-		// Remove prefix "Bearer " from auth token
-		tokenString := strings.TrimPrefix(auth, "Bearer ")
-		if tokenString == auth {
-			log.Println("Token does not have the expected 'Bearer ' prefix")
-			c.AbortWithStatus(http.StatusUnauthorized)
+// RequireScope lets through callers authenticated with a token, and API
+// keys holding scope. It must run after Guard.
+func RequireScope(scope constant.APIKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok || !principal.HasScope(scope) {
+			log.Printf("API key lacks scope %s\n", scope)
+			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
+	}
+}
 
-		token, err := verifyToken(tokenString, secret)
-		if err != nil {
-			log.Printf("Token verification failed: %v\n", err)
+// DelegationLookup returns the approver a user currently stands in for, if any.
+type DelegationLookup func(uid int) (delegatorID int, ok bool, err error)
+
+// RequireApprover lets through callers allowed to approve items and users
+// holding an active delegation from such an approver. It must run after Guard.
+func RequireApprover(delegations DelegationLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		if principal.Can(constant.ItemsApprovePermission) {
+			return
+		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			log.Printf("Token verified successfully. Claims: %+v\n", claims)
-			
-			keys := []string{"uid", "username", "firstName", "lastName", "position", "photoLink"}
+		// Which items a delegate may decide, and for whom, is up to the
+		// item service
+		_, ok, err := delegations(principal.UID)
+		if err != nil {
+			log.Printf("Delegation lookup failed: %v\n", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			log.Println("User is not an approver and holds no delegation")
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	}
+}
 
-			for _, key := range keys {
-				if value, ok := claims[key].(string); ok {
-					c.Set(key, value)
-				} else if key == "uid" {
-					if value, ok := claims[key].(float64); ok {
-						c.Set(key, value)
-					}
-				}
-			}
+// EmailVerificationLookup reports whether a user verified their email address.
+type EmailVerificationLookup func(uid int) (bool, error)
 
-			if role, ok := claims["position"].(string); !ok || role != "Admin" {
-				log.Println("User is not an admin")
-				c.AbortWithStatus(http.StatusForbidden) // 403 Forbidden
-				return
-			}
-		} else {
-			log.Println("Token claims are invalid or token is not valid")
+// RequireVerifiedEmail lets through callers, and owners of API keys, who
+// verified their email address. It must run after Guard.
+func RequireVerifiedEmail(verified EmailVerificationLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
 			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		ok, err := verified(principal.UID)
+		if err != nil {
+			log.Printf("Email verification lookup failed: %v\n", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "verify your email address first",
+			})
 		}
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms, as written in the JWT "alg" header.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// ErrNoSigningKey is returned when tokens should be signed with an
+// asymmetric key but no active key is loaded.
+var ErrNoSigningKey = errors.New("no active signing key")
+
+// Algorithm returns the algorithm new tokens are signed with, read from
+// JWT_ALGORITHM (HS256 by default).
+func Algorithm() (string, error) {
+	switch algorithm := os.Getenv("JWT_ALGORITHM"); algorithm {
+	case "":
+		return AlgorithmHS256, nil
+	case AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("JWT_ALGORITHM: unsupported algorithm %q", algorithm)
+	}
+}
+
+// SigningKey is an asymmetric key tokens are signed or verified with.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	// Private is only set on the active key.
+	Private crypto.Signer
+	Public  crypto.PublicKey
+	// Active marks the key new tokens are signed with.
+	Active bool
+}
+
+func (key SigningKey) method() jwt.SigningMethod {
+	if key.Algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// KeyLoader returns the keys currently valid for verification.
+type KeyLoader func() ([]SigningKey, error)
+
+// KeyReloadInterval is how long a KeySet trusts its keys before loading them
+// again, so that keys rotated by another instance are picked up.
+const KeyReloadInterval = time.Minute
+
+// KeySet holds the asymmetric keys tokens are signed and verified with.
+// It is safe for concurrent use.
+type KeySet struct {
+	load KeyLoader
+
+	mu       sync.RWMutex
+	keys     map[string]SigningKey
+	active   string
+	loadedAt time.Time
+}
+
+// NewKeySet creates a key set and loads its keys.
+func NewKeySet(load KeyLoader) (*KeySet, error) {
+	set := &KeySet{load: load}
+	if err := set.Reload(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Reload loads the keys again.
+func (set *KeySet) Reload() error {
+	keys, err := set.load()
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]SigningKey, len(keys))
+	active := ""
+	for _, key := range keys {
+		byID[key.ID] = key
+		if key.Active && key.Private != nil && active == "" {
+			active = key.ID
+		}
+	}
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.keys = byID
+	set.active = active
+	set.loadedAt = time.Now()
+	return nil
+}
+
+// reloadIfStale reloads the keys when they were loaded more than
+// KeyReloadInterval ago. Failures keep the current keys.
+func (set *KeySet) reloadIfStale() {
+	set.mu.RLock()
+	stale := time.Since(set.loadedAt) > KeyReloadInterval
+	set.mu.RUnlock()
+	if !stale {
+		return
+	}
+	if err := set.Reload(); err != nil {
+		log.Printf("Reloading signing keys failed: %v\n", err)
+	}
+}
+
+// SigningKey returns the key new tokens are signed with.
+func (set *KeySet) SigningKey() (SigningKey, bool) {
+	set.reloadIfStale()
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	key, ok := set.keys[set.active]
+	return key, ok
+}
+
+// VerificationKey returns the key with the given kid.
+func (set *KeySet) VerificationKey(kid string) (SigningKey, bool) {
+	set.reloadIfStale()
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	key, ok := set.keys[kid]
+	return key, ok
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set.
+func (set *KeySet) JWKS() JWKS {
+	set.reloadIfStale()
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range set.keys {
+		jwk := JWK{KeyID: key.ID, Algorithm: key.Algorithm, Use: "sig"}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+var (
+	signingKeysMu sync.RWMutex
+	signingKeys   *KeySet
+)
+
+// UseKeySet makes CreateToken sign with the active key of set instead of
+// JWT_SECRET. Passing nil goes back to JWT_SECRET.
+func UseKeySet(set *KeySet) {
+	signingKeysMu.Lock()
+	defer signingKeysMu.Unlock()
+	signingKeys = set
+}
+
+func currentKeySet() *KeySet {
+	signingKeysMu.RLock()
+	defer signingKeysMu.RUnlock()
+	return signingKeys
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/gin-gonic/gin"
+)
+
+const principalKey = "principal"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UID       int
+	Username  string
+	FirstName string
+	LastName  string
+	Position  string
+	PhotoLink string
+	// JTI and ExpiresAt identify the access token the caller presented,
+	// SessionID the login it was issued for.
+	JTI       string
+	ExpiresAt time.Time
+	SessionID uint
+	// Permissions are loaded from the caller's roles on every request.
+	Permissions []string
+	// APIKeyID and Scopes are set when the caller authenticated with an API key.
+	APIKeyID *uint
+	Scopes   []constant.APIKeyScope
+}
+
+// Can reports whether the principal holds permission.
+func (p Principal) Can(permission constant.Permission) bool {
+	return slices.Contains(p.Permissions, string(permission))
+}
+
+// HasScope reports whether the principal may act within scope. Callers
+// authenticated with a token are not limited by scopes.
+func (p Principal) HasScope(scope constant.APIKeyScope) bool {
+	return p.APIKeyID == nil || slices.Contains(p.Scopes, scope)
+}
+
+// scopedPermissions keeps the permissions the principal's scopes allow.
+func (p Principal) scopedPermissions(permissions []string) []string {
+	if p.APIKeyID == nil {
+		return permissions
+	}
+	scoped := []string{}
+	for _, scope := range p.Scopes {
+		for _, permission := range constant.APIKeyScopePermissions[scope] {
+			if slices.Contains(permissions, string(permission)) && !slices.Contains(scoped, string(permission)) {
+				scoped = append(scoped, string(permission))
+			}
+		}
+	}
+	return scoped
+}
+
+// PrincipalFrom returns the principal Guard stored in the context.
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
+
+// MustPrincipal returns the principal Guard stored in the context and panics
+// without one, like gin's MustGet. Use it on routes behind Guard.
+func MustPrincipal(c *gin.Context) Principal {
+	principal, ok := PrincipalFrom(c)
+	if !ok {
+		panic("auth: no principal in context, is the route behind Guard?")
+	}
+	return principal
+}
+
+func setPrincipal(c *gin.Context, principal Principal) {
+	c.Set(principalKey, principal)
+}
@@ -0,0 +1,131 @@
+package comment
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/item"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/twofactor"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB, twoFactor twofactor.Service) Controller {
+	return Controller{
+		Service: NewService(db, twoFactor),
+	}
+}
+
+// actorFromContext builds the acting user from the claims set by auth.Guard.
+func actorFromContext(ctx *gin.Context) item.Actor {
+	principal := auth.MustPrincipal(ctx)
+	return item.Actor{
+		ID:          principal.UID,
+		Permissions: principal.Permissions,
+	}
+}
+
+// respondError writes the response for an error returned by the service.
+func respondError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": "Comment not found",
+		})
+	case errors.Is(err, ErrNotAuthor), errors.Is(err, item.ErrForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func (controller Controller) CreateComment(ctx *gin.Context) {
+	// Bind
+	var request model.RequestComment
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Path param
+	itemID, _ := strconv.Atoi(ctx.Param("id"))
+
+	comment, err := controller.Service.Create(uint(itemID), request, actorFromContext(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data": comment,
+	})
+}
+
+func (controller Controller) FindComments(ctx *gin.Context) {
+	// Path param
+	itemID, _ := strconv.Atoi(ctx.Param("id"))
+
+	comments, err := controller.Service.FindByItemID(uint(itemID), actorFromContext(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": comments,
+	})
+}
+
+func (controller Controller) UpdateComment(ctx *gin.Context) {
+	// Bind
+	var request model.RequestComment
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Path param
+	itemID, _ := strconv.Atoi(ctx.Param("id"))
+	id, _ := strconv.Atoi(ctx.Param("commentId"))
+
+	comment, err := controller.Service.Update(uint(itemID), uint(id), request, actorFromContext(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": comment,
+	})
+}
+
+func (controller Controller) DeleteComment(ctx *gin.Context) {
+	// Path param
+	itemID, _ := strconv.Atoi(ctx.Param("id"))
+	id, _ := strconv.Atoi(ctx.Param("commentId"))
+
+	if err := controller.Service.Delete(uint(itemID), uint(id), actorFromContext(ctx)); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Deleted",
+	})
+}
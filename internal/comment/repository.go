@@ -0,0 +1,43 @@
+package comment
+
+import (
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) Create(comment *model.Comment) error {
+	return repo.Database.Create(comment).Error
+}
+
+func (repo Repository) FindByItemID(itemID uint) ([]model.Comment, error) {
+	var results []model.Comment
+	if err := repo.Database.Where("item_id = ?", itemID).Order("id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) FindByID(itemID uint, id uint) (model.Comment, error) {
+	var result model.Comment
+	err := repo.Database.Where("item_id = ?", itemID).First(&result, id).Error
+	return result, err
+}
+
+func (repo Repository) Replace(comment model.Comment) error {
+	return repo.Database.Model(&comment).Updates(comment).Error
+}
+
+func (repo Repository) Delete(id uint) error {
+	return repo.Database.Delete(&model.Comment{}, id).Error
+}
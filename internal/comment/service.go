@@ -0,0 +1,85 @@
+package comment
+
+import (
+	"errors"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/item"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/twofactor"
+
+	"gorm.io/gorm"
+)
+
+var ErrNotAuthor = errors.New("only the author or a moderator can change this comment")
+
+type Service struct {
+	Repository Repository
+	Items      item.Service
+}
+
+func NewService(db *gorm.DB, twoFactor twofactor.Service) Service {
+	return Service{
+		Repository: NewRepository(db),
+		Items:      item.NewService(db, twoFactor),
+	}
+}
+
+func (service Service) Create(itemID uint, req model.RequestComment, actor item.Actor) (model.Comment, error) {
+	if err := service.Items.Authorize(itemID, actor, item.ActionComment); err != nil {
+		return model.Comment{}, err
+	}
+
+	comment := model.Comment{
+		ItemID:   itemID,
+		AuthorID: actor.ID,
+		Body:     req.Body,
+	}
+	if err := service.Repository.Create(&comment); err != nil {
+		return model.Comment{}, err
+	}
+	return comment, nil
+}
+
+func (service Service) FindByItemID(itemID uint, actor item.Actor) ([]model.Comment, error) {
+	if err := service.Items.Authorize(itemID, actor, item.ActionView); err != nil {
+		return nil, err
+	}
+	return service.Repository.FindByItemID(itemID)
+}
+
+func (service Service) Update(itemID uint, id uint, req model.RequestComment, actor item.Actor) (model.Comment, error) {
+	comment, err := service.findEditable(itemID, id, actor)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	comment.Body = req.Body
+	if err := service.Repository.Replace(comment); err != nil {
+		return model.Comment{}, err
+	}
+	return comment, nil
+}
+
+func (service Service) Delete(itemID uint, id uint, actor item.Actor) error {
+	comment, err := service.findEditable(itemID, id, actor)
+	if err != nil {
+		return err
+	}
+	return service.Repository.Delete(comment.ID)
+}
+
+// findEditable returns the comment if the user is its author or a moderator.
+func (service Service) findEditable(itemID uint, id uint, actor item.Actor) (model.Comment, error) {
+	if err := service.Items.Authorize(itemID, actor, item.ActionComment); err != nil {
+		return model.Comment{}, err
+	}
+	comment, err := service.Repository.FindByID(itemID, id)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	if comment.AuthorID != actor.ID && !actor.Can(constant.CommentsModeratePermission) {
+		return model.Comment{}, ErrNotAuthor
+	}
+	return comment, nil
+}
@@ -0,0 +1,21 @@
+package constant
+
+// APIKeyScope limits what an API key may do on behalf of its owner.
+type APIKeyScope string
+
+const (
+	ItemsReadScope  APIKeyScope = "items:read"
+	ItemsWriteScope APIKeyScope = "items:write"
+)
+
+// APIKeyScopePermissions are the owner's permissions an API key may use
+// for each scope. Permissions not listed here are never available to keys.
+var APIKeyScopePermissions = map[APIKeyScope][]Permission{
+	ItemsReadScope:  {ItemsReadAnyPermission},
+	ItemsWriteScope: {ItemsCreatePermission, ItemsApprovePermission, ItemsDeleteAnyPermission},
+}
+
+func (s APIKeyScope) Valid() bool {
+	_, ok := APIKeyScopePermissions[s]
+	return ok
+}
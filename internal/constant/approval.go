@@ -0,0 +1,10 @@
+package constant
+
+type ApprovalStepStatus string
+
+const (
+	ApprovalStepPendingStatus   ApprovalStepStatus = "PENDING"
+	ApprovalStepApprovedStatus  ApprovalStepStatus = "APPROVED"
+	ApprovalStepRejectedStatus  ApprovalStepStatus = "REJECTED"
+	ApprovalStepCancelledStatus ApprovalStepStatus = "CANCELLED"
+)
@@ -1,9 +1,109 @@
 package constant
 
+import "slices"
+
 type ItemStatus string
 
 const (
-	ItemPendingStatus  ItemStatus = "PENDING"
-	ItemApprovedStatus ItemStatus = "APPROVED"
-	ItemRejectedStatus ItemStatus = "REJECTED"
+	ItemDraftStatus     ItemStatus = "DRAFT"
+	ItemSubmittedStatus ItemStatus = "SUBMITTED"
+	ItemPendingStatus   ItemStatus = "PENDING"
+	ItemApprovedStatus  ItemStatus = "APPROVED"
+	ItemRejectedStatus  ItemStatus = "REJECTED"
+	ItemCancelledStatus ItemStatus = "CANCELLED"
+	ItemOrderedStatus   ItemStatus = "ORDERED"
+	ItemReceivedStatus  ItemStatus = "RECEIVED"
+)
+
+// ItemActor is who is allowed to perform a status transition.
+type ItemActor string
+
+const (
+	// ItemOwnerActor is the user who created the item.
+	ItemOwnerActor ItemActor = "OWNER"
+	// ItemApproverActor is a user with approval rights for the item.
+	ItemApproverActor ItemActor = "APPROVER"
+)
+
+type ItemTransition struct {
+	To    ItemStatus
+	Actor ItemActor
+}
+
+// ItemTransitions is the item lifecycle: for each status, the statuses it
+// may move to and who may move it there. Statuses without an entry are final.
+var ItemTransitions = map[ItemStatus][]ItemTransition{
+	ItemDraftStatus: {
+		{To: ItemSubmittedStatus, Actor: ItemOwnerActor},
+		{To: ItemCancelledStatus, Actor: ItemOwnerActor},
+	},
+	ItemSubmittedStatus: {
+		{To: ItemPendingStatus, Actor: ItemApproverActor},
+		{To: ItemApprovedStatus, Actor: ItemApproverActor},
+		{To: ItemRejectedStatus, Actor: ItemApproverActor},
+		{To: ItemDraftStatus, Actor: ItemOwnerActor},
+		{To: ItemCancelledStatus, Actor: ItemOwnerActor},
+	},
+	ItemPendingStatus: {
+		{To: ItemApprovedStatus, Actor: ItemApproverActor},
+		{To: ItemRejectedStatus, Actor: ItemApproverActor},
+		{To: ItemCancelledStatus, Actor: ItemOwnerActor},
+	},
+	ItemApprovedStatus: {
+		{To: ItemOrderedStatus, Actor: ItemApproverActor},
+		{To: ItemCancelledStatus, Actor: ItemApproverActor},
+	},
+	ItemOrderedStatus: {
+		{To: ItemReceivedStatus, Actor: ItemOwnerActor},
+		{To: ItemReceivedStatus, Actor: ItemApproverActor},
+	},
+	ItemRejectedStatus: {
+		{To: ItemSubmittedStatus, Actor: ItemOwnerActor},
+		{To: ItemDraftStatus, Actor: ItemOwnerActor},
+		{To: ItemCancelledStatus, Actor: ItemOwnerActor},
+	},
+}
+
+// Valid reports whether s is one of the known item statuses.
+func (s ItemStatus) Valid() bool {
+	switch s {
+	case ItemDraftStatus, ItemSubmittedStatus, ItemPendingStatus, ItemApprovedStatus,
+		ItemRejectedStatus, ItemCancelledStatus, ItemOrderedStatus, ItemReceivedStatus:
+		return true
+	}
+	return false
+}
+
+// Editable reports whether requesters may still change an item in status s.
+func (s ItemStatus) Editable() bool {
+	return s == ItemDraftStatus || s == ItemSubmittedStatus || s == ItemPendingStatus
+}
+
+// NextStatuses returns the statuses s may move to for any of the given actors.
+func (s ItemStatus) NextStatuses(actors ...ItemActor) []ItemStatus {
+	next := []ItemStatus{}
+	for _, t := range ItemTransitions[s] {
+		if !slices.Contains(actors, t.Actor) || slices.Contains(next, t.To) {
+			continue
+		}
+		next = append(next, t.To)
+	}
+	return next
+}
+
+// CanTransition reports whether any of the given actors may move s to to.
+func (s ItemStatus) CanTransition(to ItemStatus, actors ...ItemActor) bool {
+	return slices.Contains(s.NextStatuses(actors...), to)
+}
+
+type ItemHistoryAction string
+
+const (
+	ItemCreatedAction      ItemHistoryAction = "CREATE"
+	ItemUpdatedAction      ItemHistoryAction = "UPDATE"
+	ItemStatusAction       ItemHistoryAction = "STATUS"
+	ItemApprovalStepAction ItemHistoryAction = "APPROVAL_STEP"
+	ItemDeletedAction      ItemHistoryAction = "DELETE"
+	ItemEscalatedAction    ItemHistoryAction = "ESCALATE"
+	ItemOverriddenAction   ItemHistoryAction = "OVERRIDE"
 )
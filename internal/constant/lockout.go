@@ -0,0 +1,9 @@
+package constant
+
+// LockoutKind is what failed logins are counted against.
+type LockoutKind string
+
+const (
+	AccountLockout LockoutKind = "account"
+	IPLockout      LockoutKind = "ip"
+)
@@ -0,0 +1,40 @@
+package constant
+
+type Permission string
+
+const (
+	// ItemsCreatePermission allows creating purchase requests.
+	ItemsCreatePermission Permission = "items:create"
+	// ItemsApprovePermission allows approving, rejecting and ordering any item.
+	ItemsApprovePermission Permission = "items:approve"
+	// ItemsReadAnyPermission allows reading any item, including deleted items' history.
+	ItemsReadAnyPermission Permission = "items:read:any"
+	// ItemsDeleteAnyPermission allows deleting items of other users.
+	ItemsDeleteAnyPermission Permission = "items:delete:any"
+	// ItemsOverridePermission allows changing read-only items.
+	ItemsOverridePermission Permission = "items:override"
+	// CommentsModeratePermission allows editing and deleting other users' comments.
+	CommentsModeratePermission Permission = "comments:moderate"
+	// DelegationsManagePermission allows revoking other users' delegations.
+	DelegationsManagePermission Permission = "delegations:manage"
+	// ApprovalLevelsManagePermission allows changing the approval chain levels.
+	ApprovalLevelsManagePermission Permission = "approval-levels:manage"
+	// RulesManagePermission allows managing auto-approval and routing rules.
+	RulesManagePermission Permission = "rules:manage"
+	// RolesManagePermission allows assigning roles to users.
+	RolesManagePermission Permission = "roles:manage"
+	// TokensRevokePermission allows revoking access tokens of any user.
+	TokensRevokePermission Permission = "tokens:revoke"
+	// KeysManagePermission allows listing and rotating token signing keys.
+	KeysManagePermission Permission = "keys:manage"
+	// SettingsManagePermission allows changing system settings.
+	SettingsManagePermission Permission = "settings:manage"
+	// LockoutsManagePermission allows viewing and clearing login lockouts.
+	LockoutsManagePermission Permission = "lockouts:manage"
+	// SessionsManagePermission allows listing and signing out sessions of any user.
+	SessionsManagePermission Permission = "sessions:manage"
+	// UsersManagePermission allows listing, editing and deactivating users.
+	UsersManagePermission Permission = "users:manage"
+	// InvitationsManagePermission allows inviting users to register and revoking invitations.
+	InvitationsManagePermission Permission = "invitations:manage"
+)
@@ -2,7 +2,8 @@ package constant
 
 type Role string
 
+// Built-in roles, seeded by the roles migration. Registered users get User.
 const (
 	Admin Role = "Admin"
-	User Role = "User"
+	User  Role = "User"
 )
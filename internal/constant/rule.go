@@ -0,0 +1,14 @@
+package constant
+
+type RuleAction string
+
+const (
+	// RuleAutoApproveAction approves a matching item without human approvers.
+	RuleAutoApproveAction RuleAction = "AUTO_APPROVE"
+	// RuleRouteAction adds an approval step for the rule's approver position.
+	RuleRouteAction RuleAction = "ROUTE"
+)
+
+func (a RuleAction) Valid() bool {
+	return a == RuleAutoApproveAction || a == RuleRouteAction
+}
@@ -0,0 +1,110 @@
+package delegation
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB) Controller {
+	return Controller{
+		Service: NewService(db),
+	}
+}
+
+func (controller Controller) CreateDelegation(ctx *gin.Context) {
+	// Bind
+	var request model.RequestCreateDelegation
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get delegator from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	delegation, err := controller.Service.Create(request, uid)
+	if err != nil {
+		if errors.Is(err, ErrSelfDelegation) || errors.Is(err, ErrUnknownDelegate) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, ErrNotApprover) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data": delegation,
+	})
+}
+
+func (controller Controller) FindDelegations(ctx *gin.Context) {
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	delegations, err := controller.Service.FindByUser(uid)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": delegations,
+	})
+}
+
+func (controller Controller) RevokeDelegation(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	// get uid and permissions from context
+	uid := auth.MustPrincipal(ctx).UID
+	canManage := auth.HasPermission(ctx, constant.DelegationsManagePermission)
+
+	if err := controller.Service.Revoke(uint(id), uid, canManage); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"message": "Delegation not found",
+			})
+		case errors.Is(err, ErrNotDelegator):
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Revoked",
+	})
+}
@@ -0,0 +1,85 @@
+package delegation
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) UserExists(id int) (bool, error) {
+	var count int64
+	err := repo.Database.Model(&model.User{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// canApproveAny is a subquery selecting a row when the user in userColumn
+// holds the permission to approve any item.
+func (repo Repository) canApproveAny(userColumn string) *gorm.DB {
+	return repo.Database.
+		Table("user_roles").
+		Select("1").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("user_roles.user_id = "+userColumn+" AND permissions.name = ?", constant.ItemsApprovePermission)
+}
+
+// IsApprover reports whether the user may approve any item, or holds a
+// position approval levels or routing rules send items to.
+func (repo Repository) IsApprover(id int) (bool, error) {
+	var count int64
+	err := repo.Database.
+		Model(&model.User{}).
+		Where("users.id = ?", id).
+		Where("EXISTS (?) OR (users.position <> '' AND (users.position IN (SELECT approver_position FROM approval_levels) OR users.position IN (SELECT route_to FROM rules WHERE enabled)))",
+			repo.canApproveAny("users.id")).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (repo Repository) Create(delegation *model.Delegation) error {
+	return repo.Database.Create(delegation).Error
+}
+
+func (repo Repository) FindByID(id uint) (model.Delegation, error) {
+	var result model.Delegation
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+// FindByUser returns the delegations the user gave or received.
+func (repo Repository) FindByUser(uid int) ([]model.Delegation, error) {
+	var results []model.Delegation
+	err := repo.Database.Where("delegator_id = ? OR delegate_id = ?", uid, uid).Order("id desc").Find(&results).Error
+	return results, err
+}
+
+// FindActiveByDelegate returns the delegations in effect for the delegate at
+// the given time, together with each delegator's position and approval rights.
+func (repo Repository) FindActiveByDelegate(delegateID int, at time.Time) ([]model.Delegation, error) {
+	var results []model.Delegation
+	err := repo.Database.
+		Select("delegations.*, users.position AS delegator_position, EXISTS (?) AS delegator_can_approve", repo.canApproveAny("delegations.delegator_id")).
+		Joins("JOIN users ON users.id = delegations.delegator_id").
+		Where("delegations.delegate_id = ? AND delegations.revoked_at IS NULL", delegateID).
+		Where("delegations.starts_at <= ? AND delegations.ends_at > ?", at, at).
+		Order("delegations.id").
+		Find(&results).Error
+	return results, err
+}
+
+func (repo Repository) Revoke(id uint, at time.Time) error {
+	return repo.Database.Model(&model.Delegation{}).Where("id = ?", id).Update("revoked_at", at).Error
+}
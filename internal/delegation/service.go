@@ -0,0 +1,101 @@
+package delegation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSelfDelegation  = errors.New("you cannot delegate to yourself")
+	ErrUnknownDelegate = errors.New("delegate does not exist")
+	ErrNotDelegator    = errors.New("only the delegator or an admin can revoke this delegation")
+	ErrNotApprover     = errors.New("only approvers can delegate their approval rights")
+)
+
+type Service struct {
+	Repository Repository
+}
+
+func NewService(db *gorm.DB) Service {
+	return Service{
+		Repository: NewRepository(db),
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	return service
+}
+
+func (service Service) Create(req model.RequestCreateDelegation, delegatorID int) (model.Delegation, error) {
+	if req.DelegateID == delegatorID {
+		return model.Delegation{}, ErrSelfDelegation
+	}
+	approver, err := service.Repository.IsApprover(delegatorID)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	if !approver {
+		return model.Delegation{}, ErrNotApprover
+	}
+	exists, err := service.Repository.UserExists(req.DelegateID)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	if !exists {
+		return model.Delegation{}, ErrUnknownDelegate
+	}
+
+	delegation := model.Delegation{
+		DelegatorID: delegatorID,
+		DelegateID:  req.DelegateID,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		MaxAmount:   req.MaxAmount,
+	}
+	if err := service.Repository.Create(&delegation); err != nil {
+		return model.Delegation{}, err
+	}
+	return delegation, nil
+}
+
+func (service Service) FindByUser(uid int) ([]model.Delegation, error) {
+	return service.Repository.FindByUser(uid)
+}
+
+// Revoke ends a delegation. Only its delegator, or a user allowed to manage
+// delegations, may revoke it.
+func (service Service) Revoke(id uint, uid int, canManage bool) error {
+	delegation, err := service.Repository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if delegation.DelegatorID != uid && !canManage {
+		return ErrNotDelegator
+	}
+	return service.Repository.Revoke(id, time.Now())
+}
+
+// Active returns the delegations the user holds right now.
+func (service Service) Active(delegateID int) ([]model.Delegation, error) {
+	return service.Repository.FindActiveByDelegate(delegateID, time.Now())
+}
+
+// ActiveApproverDelegator returns an approver the user currently stands in for, if any.
+func (service Service) ActiveApproverDelegator(delegateID int) (int, bool, error) {
+	delegations, err := service.Active(delegateID)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, d := range delegations {
+		if d.DelegatorCanApprove {
+			return d.DelegatorID, true, nil
+		}
+	}
+	return 0, false, nil
+}
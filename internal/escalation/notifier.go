@@ -0,0 +1,21 @@
+package escalation
+
+import (
+	"log"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+)
+
+// Notifier tells the escalation approvers about an item that breached the SLA.
+type Notifier interface {
+	NotifyEscalation(approverIDs []int, item model.Item) error
+}
+
+// LogNotifier writes escalations to the application log.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyEscalation(approverIDs []int, item model.Item) error {
+	log.Printf("Escalation: item %d (%s) has been %s since %s, notifying approvers %v\n",
+		item.ID, item.Title, item.Status, item.StatusChangedAt.Format("2006-01-02 15:04"), approverIDs)
+	return nil
+}
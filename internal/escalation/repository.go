@@ -0,0 +1,55 @@
+package escalation
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+// FindStale returns the items waiting for approval since before the given
+// time that were not escalated yet.
+func (repo Repository) FindStale(before time.Time) ([]model.Item, error) {
+	var results []model.Item
+	err := repo.Database.
+		Where("status IN (?)", []constant.ItemStatus{constant.ItemSubmittedStatus, constant.ItemPendingStatus}).
+		Where("status_changed_at < ? AND escalated_at IS NULL", before).
+		Order("status_changed_at").
+		Find(&results).Error
+	return results, err
+}
+
+// MarkEscalated flags the item as escalated, reassigning it when assigneeID
+// is set, and records it in the item history. It reports false when the
+// item changed since it was found.
+func (repo Repository) MarkEscalated(item model.Item, at time.Time, assigneeID *int, history model.ItemHistory) (bool, error) {
+	escalated := false
+	err := repo.Database.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{"escalated_at": at}
+		if assigneeID != nil {
+			updates["assignee_id"] = *assigneeID
+		}
+		result := tx.Model(&model.Item{}).
+			Where("id = ? AND status = ? AND escalated_at IS NULL", item.ID, item.Status).
+			Updates(updates)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		escalated = true
+		return tx.Create(&history).Error
+	})
+	return escalated, err
+}
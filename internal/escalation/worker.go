@@ -0,0 +1,163 @@
+package escalation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Config struct {
+	// SLA is how long an item may wait for approval before it is escalated.
+	SLA time.Duration
+	// Interval is how often the worker looks for stale items.
+	Interval time.Duration
+	// Approvers are the users notified about escalated items.
+	Approvers []int
+	// ReassignTo, when set, becomes the assignee of escalated items.
+	ReassignTo *int
+}
+
+// ConfigFromEnv reads the worker configuration from ESCALATION_SLA,
+// ESCALATION_INTERVAL, ESCALATION_APPROVERS (comma separated user ids) and
+// ESCALATION_REASSIGN_TO.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		SLA:      72 * time.Hour,
+		Interval: 15 * time.Minute,
+	}
+
+	if value := os.Getenv("ESCALATION_SLA"); value != "" {
+		sla, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("ESCALATION_SLA: %w", err)
+		}
+		if sla > 0 {
+			config.SLA = sla
+		} else {
+			log.Printf("Invalid ESCALATION_SLA %q, using the default\n", value)
+		}
+	}
+	if value := os.Getenv("ESCALATION_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("ESCALATION_INTERVAL: %w", err)
+		}
+		// Tickers panic on non-positive intervals
+		if interval > 0 {
+			config.Interval = interval
+		} else {
+			log.Printf("Invalid ESCALATION_INTERVAL %q, using the default\n", value)
+		}
+	}
+	if value := os.Getenv("ESCALATION_APPROVERS"); value != "" {
+		for _, id := range strings.Split(value, ",") {
+			approver, err := strconv.Atoi(strings.TrimSpace(id))
+			if err != nil {
+				return config, fmt.Errorf("ESCALATION_APPROVERS: %w", err)
+			}
+			config.Approvers = append(config.Approvers, approver)
+		}
+	}
+	if value := os.Getenv("ESCALATION_REASSIGN_TO"); value != "" {
+		assignee, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("ESCALATION_REASSIGN_TO: %w", err)
+		}
+		config.ReassignTo = &assignee
+	}
+
+	return config, nil
+}
+
+// Worker periodically escalates items that waited for approval longer than the SLA.
+type Worker struct {
+	Repository Repository
+	Notifier   Notifier
+	Config     Config
+}
+
+func NewWorker(db *gorm.DB, config Config, notifier Notifier) Worker {
+	return Worker{
+		Repository: NewRepository(db),
+		Notifier:   notifier,
+		Config:     config,
+	}
+}
+
+// Run escalates stale items every interval until ctx is cancelled.
+func (worker Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := worker.EscalateStale(ctx); err != nil {
+			log.Printf("Escalation run failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Escalation worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EscalateStale marks, notifies and optionally reassigns every stale item.
+func (worker Worker) EscalateStale(ctx context.Context) error {
+	now := time.Now()
+	items, err := worker.Repository.FindStale(now.Add(-worker.Config.SLA))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Requesters never approve their own items, so theirs stay unassigned
+		assigneeID := worker.Config.ReassignTo
+		if assigneeID != nil && *assigneeID == item.OwnerID {
+			assigneeID = nil
+		}
+		after := map[string]any{"escalated_at": now}
+		if assigneeID != nil {
+			after["assignee_id"] = *assigneeID
+		}
+		value, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+		history := model.ItemHistory{
+			ItemID:   item.ID,
+			Action:   constant.ItemEscalatedAction,
+			NewValue: model.JSONText(value),
+			Reason:   fmt.Sprintf("waiting in %s for longer than %s", item.Status, worker.Config.SLA),
+		}
+
+		escalated, err := worker.Repository.MarkEscalated(item, now, assigneeID, history)
+		if err != nil {
+			return err
+		}
+		if !escalated {
+			continue
+		}
+
+		item.EscalatedAt = &now
+		if err := worker.Notifier.NotifyEscalation(worker.Config.Approvers, item); err != nil {
+			log.Printf("Escalation notification for item %d failed: %v\n", item.ID, err)
+		}
+	}
+	return nil
+}
@@ -3,10 +3,14 @@ package item
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
+	"github.com/Kiratopat-s/workflow/internal/approval"
+	"github.com/Kiratopat-s/workflow/internal/auth"
 	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/twofactor"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
@@ -16,9 +20,9 @@ type Controller struct {
 	Service Service
 }
 
-func NewController(db *gorm.DB) Controller {
+func NewController(db *gorm.DB, twoFactor twofactor.Service) Controller {
 	return Controller{
-		Service: NewService(db),
+		Service: NewService(db, twoFactor),
 	}
 }
 
@@ -37,6 +41,8 @@ func msgForTag(tag, param string) string {
 		return fmt.Sprintf("Number must greater than %v", param)
 	case "gte":
 		return fmt.Sprintf("Number must greater than or equal %v", param)
+	case "required_if":
+		return fmt.Sprintf("Required when %v", param)
 	}
 	return ""
 }
@@ -53,6 +59,59 @@ func getValidationErrors(err error) []ApiError {
 	return nil
 }
 
+// actorFromContext builds the acting user from the claims set by auth.Guard.
+func actorFromContext(ctx *gin.Context) Actor {
+	principal := auth.MustPrincipal(ctx)
+	return Actor{
+		ID:          principal.UID,
+		Permissions: principal.Permissions,
+	}
+}
+
+// respondItemError writes the response for an error returned by the item service.
+func respondItemError(ctx *gin.Context, err error) {
+	var (
+		transitionErr TransitionError
+		invalidErr    InvalidStatusError
+	)
+	switch {
+	case errors.As(err, &transitionErr):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": transitionErr.Error(),
+			"item_id": transitionErr.ItemID,
+			"allowed": transitionErr.Allowed,
+		})
+	case errors.As(err, &invalidErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": invalidErr.Error(),
+		})
+	case errors.Is(err, ErrReasonRequired):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, approval.ErrNotApprover):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrStatusChanged), errors.Is(err, ErrNotRejected), errors.Is(err, ErrItemLocked):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": "Item not found",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
 func (controller Controller) CreateItem(ctx *gin.Context) {
 	// Bind
 	var request model.RequestCreateItem
@@ -66,8 +125,7 @@ func (controller Controller) CreateItem(ctx *gin.Context) {
 
 	// Create item
 	// get owner_id from context
-	ownerIdFloat := ctx.MustGet("uid").(float64)
-	ownerId := int(ownerIdFloat)
+	ownerId := auth.MustPrincipal(ctx).UID
 	item, err := controller.Service.Create(request, ownerId)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -86,7 +144,7 @@ func (controller Controller) CreateItem(ctx *gin.Context) {
 func (controller Controller) FindAllItem(ctx *gin.Context) {
 
 	
-	items, err := controller.Service.FindAll()
+	items, err := controller.Service.FindAll(actorFromContext(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"message": err,
@@ -104,7 +162,7 @@ func (controller Controller) FindItemByID(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
 
 	// Find item
-	item, err := controller.Service.FindByID(uint(id))
+	item, err := controller.Service.FindByID(uint(id), actorFromContext(ctx))
 	if err != nil {
 		// Check if the error is because the record was not found
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -145,14 +203,36 @@ func (controller Controller) UpdateItem(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
 
 	// Update item
-	item, err := controller.Service.UpdateItem(uint(id), request)
+	item, err := controller.Service.UpdateItem(uint(id), request, actorFromContext(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err,
+		respondItemError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": item,
+	})
+}
+
+func (controller Controller) OverrideItem(ctx *gin.Context) {
+	// Bind
+	var request model.RequestOverrideItem
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": getValidationErrors(err),
 		})
 		return
 	}
 
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	item, err := controller.Service.Override(uint(id), request, actorFromContext(ctx))
+	if err != nil {
+		respondItemError(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"data": item,
 	})
@@ -166,7 +246,7 @@ func (controller Controller) UpdateItemStatus(ctx *gin.Context) {
 	
 		if err := ctx.Bind(&request); err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{
-				"message": err,
+				"message": getValidationErrors(err),
 			})
 			return
 		}
@@ -175,11 +255,9 @@ func (controller Controller) UpdateItemStatus(ctx *gin.Context) {
 		id, _ := strconv.Atoi(ctx.Param("id"))
 	
 		// Update status
-		item, err := controller.Service.UpdateStatus(uint(id), request.Status)
+		item, err := controller.Service.UpdateStatus(uint(id), request.Status, request.Reason, actorFromContext(ctx))
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"message": err,
-			})
+			respondItemError(ctx, err)
 			return
 		}
 	
@@ -193,10 +271,8 @@ func (controller Controller) DeleteItem(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
 
 	// Delete
-	if err := controller.Service.Delete(uint(id)); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err,
-		})
+	if err := controller.Service.Delete(uint(id), ctx.Query("reason"), actorFromContext(ctx)); err != nil {
+		respondItemError(ctx, err)
 		return
 	}
 
@@ -212,17 +288,15 @@ func (controller Controller) UpdateManyItemsStatus(ctx *gin.Context) {
 	var request model.RequestPatchManyItemStatus
 	if err := ctx.Bind(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
-			"message": err,
+			"message": getValidationErrors(err),
 		})
 		return
 	}
 
 	// Update status
-	err := controller.Service.UpdateManyStatus(request.IDs, request.Status )
+	err := controller.Service.UpdateManyStatus(request.IDs, request.Status, request.Reason, actorFromContext(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err,
-		})
+		respondItemError(ctx, err)
 		return
 	}
 
@@ -238,23 +312,15 @@ func (controller Controller) DeleteManyItems(ctx *gin.Context) {
 	var request model.RequestDeleteManyItems
 	if err := ctx.Bind(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
-			"message": err,
+			"message": getValidationErrors(err),
 		})
 		return
 	}
 
-	// get position and uid from context
-	ownerIdFloat := ctx.MustGet("uid").(float64)
-	ownerId := int(ownerIdFloat)
-	userPostion := ctx.MustGet("position").(string)
-
-
 	// Delete
-	err := controller.Service.DeleteMany(request.IDs,ownerId,userPostion)
+	err := controller.Service.DeleteMany(request.IDs, request.Reason, actorFromContext(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"message": err,
-		})
+		respondItemError(ctx, err)
 		return
 	}
 
@@ -266,8 +332,7 @@ func (controller Controller) DeleteManyItems(ctx *gin.Context) {
 
 func (controller Controller) CountItemsStatusByUser(ctx *gin.Context) {
 	// get owner_id from context
-	ownerIdFloat := ctx.MustGet("uid").(float64)
-	ownerId := int(ownerIdFloat)
+	ownerId := auth.MustPrincipal(ctx).UID
 
 	// Count
 	counts, err := controller.Service.CountItemsStatusByUser(ownerId)
@@ -282,3 +347,96 @@ func (controller Controller) CountItemsStatusByUser(ctx *gin.Context) {
 		"data": counts,
 	})
 }
+
+func (controller Controller) FindItemHistory(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	history, err := controller.Service.FindHistory(uint(id), actorFromContext(ctx))
+	if err != nil {
+		respondItemError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": history,
+	})
+}
+
+func (controller Controller) ResubmitItem(ctx *gin.Context) {
+	// Bind
+	var request model.RequestResubmitItem
+	if err := ctx.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	item, err := controller.Service.Resubmit(uint(id), request, actorFromContext(ctx))
+	if err != nil {
+		respondItemError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": item,
+	})
+}
+
+func (controller Controller) FindItemRevisions(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	revisions, err := controller.Service.FindRevisions(uint(id), actorFromContext(ctx))
+	if err != nil {
+		respondItemError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": revisions,
+	})
+}
+
+func (controller Controller) DiffItemRevisions(ctx *gin.Context) {
+	// Bind
+	var request model.RequestRevisionDiff
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	diff, err := controller.Service.DiffRevisions(uint(id), request.From, request.To, actorFromContext(ctx))
+	if err != nil {
+		respondItemError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": diff,
+	})
+}
+
+func (controller Controller) FindItemApprovalSteps(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	steps, err := controller.Service.FindApprovalSteps(uint(id), actorFromContext(ctx))
+	if err != nil {
+		respondItemError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": steps,
+	})
+}
@@ -0,0 +1,98 @@
+package item
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Kiratopat-s/workflow/internal/approval"
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+)
+
+// Actor is the authenticated user performing an action on items. Their
+// position is not part of it: approvals match the position currently stored,
+// not the one their token was issued with.
+type Actor struct {
+	ID          int
+	Permissions []string
+}
+
+// Can reports whether the actor holds permission.
+func (actor Actor) Can(permission constant.Permission) bool {
+	return slices.Contains(actor.Permissions, string(permission))
+}
+
+// roles returns the lifecycle roles the actor holds for the given item.
+func (actor Actor) roles(item model.Item) []constant.ItemActor {
+	roles := []constant.ItemActor{}
+	if item.OwnerID == actor.ID {
+		roles = append(roles, constant.ItemOwnerActor)
+	}
+	if actor.Can(constant.ItemsApprovePermission) {
+		roles = append(roles, constant.ItemApproverActor)
+	}
+	return roles
+}
+
+// authority is what an actor may do to a particular item.
+type authority struct {
+	roles []constant.ItemActor
+	// decider is who approval steps are decided as
+	decider approval.Decider
+}
+
+// ErrStatusChanged is returned when an item's status changed while it was being updated.
+var ErrStatusChanged = errors.New("item status was changed by someone else, please reload")
+
+// ErrReasonRequired is returned when an item is rejected without a reason.
+var ErrReasonRequired = errors.New("a reason is required to reject an item")
+
+// ErrNotRejected is returned when resubmitting an item that was not rejected.
+var ErrNotRejected = errors.New("only rejected items can be resubmitted")
+
+// ErrItemLocked is returned when changing an item that is no longer open for changes.
+var ErrItemLocked = errors.New("item can no longer be changed in its current status")
+
+// TransitionError is returned when an item cannot move to the requested status.
+type TransitionError struct {
+	ItemID  uint
+	From    constant.ItemStatus
+	To      constant.ItemStatus
+	Allowed []constant.ItemStatus
+}
+
+func (e TransitionError) Error() string {
+	return fmt.Sprintf("item %d cannot move from %s to %s", e.ItemID, e.From, e.To)
+}
+
+// InvalidStatusError is returned when the requested status is not a known status.
+type InvalidStatusError struct {
+	Status constant.ItemStatus
+}
+
+func (e InvalidStatusError) Error() string {
+	return fmt.Sprintf("unknown item status %q", e.Status)
+}
+
+// checkTransition validates that an actor holding roles may move item to status.
+func checkTransition(item model.Item, status constant.ItemStatus, roles []constant.ItemActor) error {
+	if !status.Valid() {
+		return InvalidStatusError{Status: status}
+	}
+
+	if !item.Status.CanTransition(status, roles...) {
+		return TransitionError{
+			ItemID:  item.ID,
+			From:    item.Status,
+			To:      status,
+			Allowed: item.Status.NextStatuses(roles...),
+		}
+	}
+	return nil
+}
+
+// awaitingApproval reports whether the item is in the hands of its approvers.
+func awaitingApproval(item model.Item) bool {
+	return item.Status == constant.ItemSubmittedStatus || item.Status == constant.ItemPendingStatus
+}
@@ -0,0 +1,56 @@
+package item
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+
+	"gorm.io/gorm"
+)
+
+// Action is something a user asks to do with a single item.
+type Action string
+
+const (
+	ActionView         Action = "view"
+	ActionUpdate       Action = "update"
+	ActionChangeStatus Action = "change_status"
+	ActionDelete       Action = "delete"
+	ActionOverride     Action = "override"
+	ActionComment      Action = "comment"
+)
+
+// ErrForbidden is returned when a user may see an item but not perform the action.
+var ErrForbidden = errors.New("you are not allowed to do this with the item")
+
+// Authorize decides whether actor, holding roles on an item (see
+// Service.authority), may perform action on it. Users without any role on
+// the item get gorm.ErrRecordNotFound so the item's existence is not leaked;
+// users who can see the item but not perform action get ErrForbidden.
+// Status changes are further restricted by the item lifecycle.
+func Authorize(action Action, actor Actor, roles []constant.ItemActor) error {
+	isOwner := slices.Contains(roles, constant.ItemOwnerActor)
+	isApprover := slices.Contains(roles, constant.ItemApproverActor)
+
+	if !isOwner && !isApprover && !actor.Can(constant.ItemsReadAnyPermission) {
+		return gorm.ErrRecordNotFound
+	}
+
+	allowed := false
+	switch action {
+	case ActionView, ActionComment, ActionChangeStatus:
+		allowed = true
+	case ActionUpdate:
+		allowed = isOwner
+	case ActionDelete:
+		allowed = isOwner || actor.Can(constant.ItemsDeleteAnyPermission)
+	case ActionOverride:
+		allowed = actor.Can(constant.ItemsOverridePermission)
+	}
+
+	if !allowed {
+		return ErrForbidden
+	}
+	return nil
+}
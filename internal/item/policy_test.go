@@ -0,0 +1,75 @@
+package item
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+
+	"gorm.io/gorm"
+)
+
+func TestAuthorize(t *testing.T) {
+	var (
+		owner    = []constant.ItemActor{constant.ItemOwnerActor}
+		approver = []constant.ItemActor{constant.ItemApproverActor}
+		none     = []constant.ItemActor{}
+	)
+	actor := func(permissions ...constant.Permission) Actor {
+		names := make([]string, len(permissions))
+		for i, p := range permissions {
+			names[i] = string(p)
+		}
+		return Actor{ID: 1, Permissions: names}
+	}
+
+	tests := []struct {
+		name   string
+		action Action
+		actor  Actor
+		roles  []constant.ItemActor
+		want   error
+	}{
+		{"stranger cannot see", ActionView, actor(), none, gorm.ErrRecordNotFound},
+		{"stranger cannot update", ActionUpdate, actor(), none, gorm.ErrRecordNotFound},
+		{"stranger cannot delete", ActionDelete, actor(), none, gorm.ErrRecordNotFound},
+		{"stranger cannot comment", ActionComment, actor(), none, gorm.ErrRecordNotFound},
+
+		{"owner views", ActionView, actor(), owner, nil},
+		{"owner comments", ActionComment, actor(), owner, nil},
+		{"owner updates", ActionUpdate, actor(), owner, nil},
+		{"owner changes status", ActionChangeStatus, actor(), owner, nil},
+		{"owner deletes", ActionDelete, actor(), owner, nil},
+		{"owner cannot override", ActionOverride, actor(), owner, ErrForbidden},
+
+		{"approver views", ActionView, actor(), approver, nil},
+		{"approver changes status", ActionChangeStatus, actor(), approver, nil},
+		{"approver cannot update", ActionUpdate, actor(), approver, ErrForbidden},
+		{"approver cannot delete", ActionDelete, actor(), approver, ErrForbidden},
+
+		{"reader views", ActionView, actor(constant.ItemsReadAnyPermission), none, nil},
+		{"reader cannot update", ActionUpdate, actor(constant.ItemsReadAnyPermission), none, ErrForbidden},
+		{"reader cannot delete", ActionDelete, actor(constant.ItemsReadAnyPermission), none, ErrForbidden},
+		{"reader with delete any deletes", ActionDelete, actor(constant.ItemsReadAnyPermission, constant.ItemsDeleteAnyPermission), none, nil},
+		{"delete any alone cannot see", ActionDelete, actor(constant.ItemsDeleteAnyPermission), none, gorm.ErrRecordNotFound},
+		{"approver with delete any deletes", ActionDelete, actor(constant.ItemsDeleteAnyPermission), approver, nil},
+
+		{"override needs the permission", ActionOverride, actor(constant.ItemsReadAnyPermission), none, ErrForbidden},
+		{"overrider overrides", ActionOverride, actor(constant.ItemsOverridePermission), approver, nil},
+		{"override alone cannot see", ActionOverride, actor(constant.ItemsOverridePermission), none, gorm.ErrRecordNotFound},
+
+		{"unknown action is forbidden", Action("archive"), actor(), owner, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Authorize(tt.action, tt.actor, tt.roles)
+			if tt.want == nil && err != nil {
+				t.Fatalf("Authorize() = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Authorize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package item
 
 import (
 	"errors"
+	"time"
 
+	"github.com/Kiratopat-s/workflow/internal/constant"
 	"github.com/Kiratopat-s/workflow/internal/model"
 
 	"gorm.io/gorm"
@@ -50,6 +52,39 @@ func (repo Repository) FindAll() ([]model.Item, error) {
 	return results, nil
 }
 
+// FindOwnedOrApprovable returns, newest first, the items of uid and the
+// items of others awaiting approval that are assigned to uid or have an open
+// approval step for one of positions. With anyAwaiting every item of others
+// awaiting approval is returned instead.
+func (repo Repository) FindOwnedOrApprovable(uid int, positions []string, anyAwaiting bool) ([]model.Item, error) {
+	awaiting := []constant.ItemStatus{constant.ItemSubmittedStatus, constant.ItemPendingStatus}
+	others := repo.Database.Where("status IN ? AND owner_id <> ?", awaiting, uid)
+	if !anyAwaiting {
+		others = others.Where(repo.Database.
+			Where("assignee_id = ?", uid).
+			Or("EXISTS (SELECT 1 FROM approval_steps WHERE approval_steps.item_id = items.id AND approval_steps.status = ? AND approval_steps.approver_position IN ?)",
+				constant.ApprovalStepPendingStatus, positions))
+	}
+
+	var results []model.Item
+	err := repo.Database.
+		Where("owner_id = ?", uid).
+		Or(others).
+		Order("id desc").
+		Find(&results).Error
+	return results, err
+}
+
+// FindUserPosition returns the position a user currently holds.
+func (repo Repository) FindUserPosition(uid int) (string, error) {
+	var positions []string
+	err := repo.Database.Model(&model.User{}).Where("id = ?", uid).Pluck("position", &positions).Error
+	if err != nil || len(positions) == 0 {
+		return "", err
+	}
+	return positions[0], nil
+}
+
 func (repo Repository) FindByID(id uint) (model.Item, error) {
 	var result model.Item
 
@@ -76,16 +111,67 @@ func (repo Repository) Delete(id uint) error {
 	return repo.Database.Delete(&model.Item{}, id).Error
 }
 
-func (repo Repository) UpdateManyStatus(id []int, status string) error {
-	return repo.Database.Model(&model.Item{}).Where("id IN (?) AND status = ?", id, "PENDING").Update("status", status).Error
+func (repo Repository) FindByIDs(ids []int) ([]model.Item, error) {
+	var results []model.Item
+	if err := repo.Database.Where("id IN (?)", ids).Order("id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// UpdateStatus moves an item from one status to another. It only touches the
+// row while it is still in the expected status and reports whether it did.
+func (repo Repository) UpdateStatus(id uint, from constant.ItemStatus, to constant.ItemStatus) (bool, error) {
+	result := repo.Database.Model(&model.Item{}).Where("id = ? AND status = ?", id, from).Updates(map[string]any{
+		"status":            to,
+		"status_changed_at": time.Now(),
+		"escalated_at":      nil,
+		"assignee_id":       nil,
+	})
+	return result.RowsAffected > 0, result.Error
 }
 
 func (repo Repository) DeleteMany(id []int) error {
 	return repo.Database.Where("id IN (?)", id).Delete(&model.Item{}).Error
 }
 
-func (repo Repository) DeleteManyByUserId(ids []int, ownerID int) error {
-	return repo.Database.Where("id IN (?) AND owner_id = ?", ids, ownerID).Delete(&model.Item{}).Error
+func (repo Repository) FindByIDsAndOwner(ids []int, ownerID int) ([]model.Item, error) {
+	var results []model.Item
+	if err := repo.Database.Where("id IN (?) AND owner_id = ?", ids, ownerID).Order("id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) CreateHistory(history *model.ItemHistory) error {
+	return repo.Database.Create(history).Error
+}
+
+func (repo Repository) FindHistory(itemID uint) ([]model.ItemHistory, error) {
+	var results []model.ItemHistory
+	if err := repo.Database.Where("item_id = ?", itemID).Order("id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// CreateRevision stores a snapshot of the item as its next revision.
+func (repo Repository) CreateRevision(revision *model.ItemRevision) error {
+	var latest int
+	err := repo.Database.Model(&model.ItemRevision{}).Select("COALESCE(MAX(revision), 0)").Where("item_id = ?", revision.ItemID).Scan(&latest).Error
+	if err != nil {
+		return err
+	}
+	revision.Revision = latest + 1
+	return repo.Database.Create(revision).Error
+}
+
+func (repo Repository) FindRevisions(itemID uint) ([]model.ItemRevision, error) {
+	var results []model.ItemRevision
+	if err := repo.Database.Where("item_id = ?", itemID).Order("revision").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
 }
 
 func (repo Repository) CountItemsStatusByUser(ownerID int) (map[string]int, error) {
@@ -1,22 +1,54 @@
 package item
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/approval"
 	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/delegation"
 	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/rule"
+	"github.com/Kiratopat-s/workflow/internal/twofactor"
 
 	"gorm.io/gorm"
 )
 
 type Service struct {
 	Repository Repository
+	Approval   approval.Service
+	Delegation delegation.Service
+	Rules      rule.Service
+	TwoFactor  twofactor.Service
 }
 
-func NewService(db *gorm.DB) Service {
+func NewService(db *gorm.DB, twoFactor twofactor.Service) Service {
 	return Service{
 		Repository: NewRepository(db),
+		Approval:   approval.NewService(db),
+		Delegation: delegation.NewService(db),
+		Rules:      rule.NewService(db),
+		TwoFactor:  twoFactor,
 	}
 }
 
+// transaction runs fn with a copy of the service bound to a single database transaction.
+func (service Service) transaction(fn func(service Service) error) error {
+	return service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		txService := service
+		txService.Repository = NewRepository(tx)
+		txService.Approval = service.Approval.WithDB(tx)
+		txService.Delegation = service.Delegation.WithDB(tx)
+		txService.Rules = service.Rules.WithDB(tx)
+		txService.TwoFactor = service.TwoFactor.WithDB(tx)
+		return fn(txService)
+	})
+}
+
 func (service Service) Create(req model.RequestCreateItem, ownerID int) (model.Item, error) {
 	// Find user id that make request to fill in owner_id
 
@@ -27,9 +59,35 @@ func (service Service) Create(req model.RequestCreateItem, ownerID int) (model.I
 		Quantity: req.Quantity,
 		Status:   constant.ItemPendingStatus,
 		OwnerID:  ownerID,
+		StatusChangedAt: time.Now(),
 	}
+	if req.Draft {
+		item.Status = constant.ItemDraftStatus
+	}
+
+	err := service.transaction(func(service Service) error {
+		if err := service.Repository.Create(&item); err != nil {
+			return err
+		}
+		entry := model.ItemHistory{ItemID: item.ID, Action: constant.ItemCreatedAction, ActorID: ownerID}
+		if err := service.record(entry, nil, itemValues(item)); err != nil {
+			return err
+		}
+		if !awaitingApproval(item) {
+			return nil
+		}
+		if err := service.snapshot(item, ownerID); err != nil {
+			return err
+		}
+		if _, err := service.Approval.Start(item); err != nil {
+			return err
+		}
 
-	if err := service.Repository.Create(&item); err != nil {
+		applied, err := service.applyRules(item)
+		item = applied
+		return err
+	})
+	if err != nil {
 		return model.Item{}, err
 	}
 
@@ -37,79 +95,704 @@ func (service Service) Create(req model.RequestCreateItem, ownerID int) (model.I
 }
 
 
-func (service Service) FindAll() ([]model.Item, error) {
-	return service.Repository.FindAll()
+// FindAll lists the items actor may see. Users allowed to read or approve
+// any item see them all, other users their own items and those they
+// currently approve, themselves or under a delegation.
+func (service Service) FindAll(actor Actor) ([]model.Item, error) {
+	if actor.Can(constant.ItemsReadAnyPermission) || actor.Can(constant.ItemsApprovePermission) {
+		return service.Repository.FindAll()
+	}
+
+	// Narrow the items down in the database, then check each of them
+	// against steps loaded in one go
+	standing, err := service.standing(actor)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := service.Repository.FindOwnedOrApprovable(actor.ID, standing.positions(), standing.approvesAny())
+	if err != nil {
+		return nil, err
+	}
+	ids := []uint{}
+	for _, item := range candidates {
+		if mayApproveAsOther(item, actor) {
+			ids = append(ids, item.ID)
+		}
+	}
+	steps, err := service.Approval.CurrentSteps(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []model.Item{}
+	for _, item := range candidates {
+		step, hasStep := steps[item.ID]
+		auth := resolveAuthority(item, actor, standing, step, hasStep)
+		if Authorize(ActionView, actor, auth.roles) == nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
 }
 
-func (service Service) FindByID(id uint) (model.Item, error) {
+func (service Service) FindByID(id uint, actor Actor) (model.Item, error) {
+	return service.find(id, actor, ActionView)
+}
+
+// find loads an item and checks that actor may perform action on it.
+func (service Service) find(id uint, actor Actor, action Action) (model.Item, error) {
 	item, err := service.Repository.FindByID(id)
 	if err != nil {
-		return item, err
+		return model.Item{}, err
+	}
+	auth, err := service.authority(item, actor)
+	if err != nil {
+		return model.Item{}, err
+	}
+	if err := Authorize(action, actor, auth.roles); err != nil {
+		return model.Item{}, err
 	}
 	return item, nil
 }
 
+// Authorize checks that actor may perform action on the item, for handlers
+// outside this package that work on an item's data.
+func (service Service) Authorize(id uint, actor Actor, action Action) error {
+	_, err := service.find(id, actor, action)
+	return err
+}
+
 
-func (service Service) UpdateItem(id uint, req model.RequestUpdateItem) (model.Item, error) {
-	// Find item
-	item, err := service.Repository.FindByID(id)
+// UpdateItem edits an item that is still open for changes. Approved,
+// rejected and closed items are read-only; see Override and Resubmit.
+func (service Service) UpdateItem(id uint, req model.RequestUpdateItem, actor Actor) (model.Item, error) {
+	var item model.Item
+	err := service.transaction(func(service Service) error {
+		// Find item
+		found, err := service.find(id, actor, ActionUpdate)
+		if err != nil {
+			return err
+		}
+		if !found.Status.Editable() {
+			return ErrItemLocked
+		}
+
+		item, err = service.applyChanges(found, req, constant.ItemUpdatedAction, req.Reason, actor)
+		return err
+	})
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	return item, nil
+}
+
+// Override lets an admin change an item whatever its status. The
+// justification and the before/after values are kept in the item history.
+func (service Service) Override(id uint, req model.RequestOverrideItem, actor Actor) (model.Item, error) {
+	var item model.Item
+	err := service.transaction(func(service Service) error {
+		// Find item
+		found, err := service.find(id, actor, ActionOverride)
+		if err != nil {
+			return err
+		}
+
+		changes := model.RequestUpdateItem{Title: req.Title, Amount: req.Amount, Quantity: req.Quantity}
+		item, err = service.applyChanges(found, changes, constant.ItemOverriddenAction, req.Justification, actor)
+		return err
+	})
 	if err != nil {
 		return model.Item{}, err
 	}
 
+	return item, nil
+}
+
+// applyChanges writes the requested changes to item and records them in
+// its history. Changing an item that is waiting for approval restarts its
+// approval chain, since earlier approvals were given for other values.
+func (service Service) applyChanges(item model.Item, req model.RequestUpdateItem, action constant.ItemHistoryAction, reason string, actor Actor) (model.Item, error) {
+	updated := item
+
 	// Fill data
 	if req.Title != nil {
-		item.Title = *req.Title
+		updated.Title = *req.Title
 	}
 	if req.Amount != nil {
-		item.Amount = *req.Amount
+		updated.Amount = *req.Amount
 	}
 	if req.Quantity != nil {
-		item.Quantity = *req.Quantity
+		updated.Quantity = *req.Quantity
+	}
+
+	before, after := changedValues(itemValues(item), itemValues(updated))
+	if len(after) == 0 {
+		return item, nil
 	}
 
 	// Replace
-	if err := service.Repository.Replace(item); err != nil {
+	if err := service.Repository.Replace(updated); err != nil {
+		return model.Item{}, err
+	}
+
+	entry := model.ItemHistory{ItemID: item.ID, Action: action, Reason: reason, ActorID: actor.ID}
+	if err := service.record(entry, before, after); err != nil {
 		return model.Item{}, err
 	}
 
+	if awaitingApproval(updated) {
+		if _, err := service.Approval.Start(updated); err != nil {
+			return model.Item{}, err
+		}
+		return service.applyRules(updated)
+	}
+	return updated, nil
+}
+
+func (service Service) UpdateStatus(id uint, status constant.ItemStatus, reason string, actor Actor) (model.Item, error) {
+	var item model.Item
+	err := service.transaction(func(service Service) error {
+		// Find item
+		found, err := service.Repository.FindByID(id)
+		if err != nil {
+			return err
+		}
+
+		item, err = service.changeStatus(found, status, reason, actor)
+		return err
+	})
+	if err != nil {
+		return model.Item{}, err
+	}
 	return item, nil
 }
 
-func (service Service) UpdateStatus(id uint, status constant.ItemStatus) (model.Item, error) {
-	// Find item
-	item, err := service.Repository.FindByID(id)
+// standing is what an actor brings to the approval of any item: the
+// position currently stored for them and the delegations they hold, which
+// only count when they may approve at all (see twofactor.Service.MayApprove).
+type standing struct {
+	mayApprove  bool
+	position    string
+	delegations []model.Delegation
+}
+
+// positions returns the positions the actor approves steps for: their own
+// and those of their delegators.
+func (standing standing) positions() []string {
+	positions := []string{}
+	if !standing.mayApprove {
+		return positions
+	}
+	if standing.position != "" {
+		positions = append(positions, standing.position)
+	}
+	for _, d := range standing.delegations {
+		positions = append(positions, d.DelegatorPosition)
+	}
+	return positions
+}
+
+// approvesAny reports whether the actor holds a delegation from a user
+// allowed to approve any item.
+func (standing standing) approvesAny() bool {
+	if !standing.mayApprove {
+		return false
+	}
+	for _, d := range standing.delegations {
+		if d.DelegatorCanApprove {
+			return true
+		}
+	}
+	return false
+}
+
+// standing loads the approval standing of actor, once for all the items a
+// request looks at.
+func (service Service) standing(actor Actor) (standing, error) {
+	mayApprove, err := service.TwoFactor.MayApprove(actor.ID)
+	if err != nil || !mayApprove {
+		return standing{}, err
+	}
+
+	// Positions change, so the one in the actor's token may be stale
+	position, err := service.Repository.FindUserPosition(actor.ID)
 	if err != nil {
+		return standing{}, err
+	}
+	delegations, err := service.Delegation.Active(actor.ID)
+	if err != nil {
+		return standing{}, err
+	}
+	return standing{mayApprove: true, position: position, delegations: delegations}, nil
+}
+
+// authority works out what actor may do to item. Besides users allowed to
+// approve any item, the approver of the item's current approval step acts as
+// its approver, and so does anyone holding an active delegation from such an
+// approver.
+func (service Service) authority(item model.Item, actor Actor) (authority, error) {
+	auth := resolveAuthority(item, actor, standing{}, model.ApprovalStep{}, false)
+	if !mayApproveAsOther(item, actor) {
+		return auth, nil
+	}
+
+	standing, err := service.standing(actor)
+	if err != nil {
+		return auth, err
+	}
+	step, hasStep, err := service.Approval.CurrentStep(item)
+	if err != nil {
+		return auth, err
+	}
+	return resolveAuthority(item, actor, standing, step, hasStep), nil
+}
+
+// mayApproveAsOther reports whether actor could approve item other than
+// through their own permission: by position, assignment or delegation.
+func mayApproveAsOther(item model.Item, actor Actor) bool {
+	// Nobody approves their own items this way, whatever their position
+	return !actor.Can(constant.ItemsApprovePermission) && awaitingApproval(item) && item.OwnerID != actor.ID
+}
+
+// resolveAuthority is authority for an actor with the given standing on an
+// item whose current approval step is step, if hasStep.
+func resolveAuthority(item model.Item, actor Actor, standing standing, step model.ApprovalStep, hasStep bool) authority {
+	auth := authority{
+		roles: actor.roles(item),
+		decider: approval.Decider{
+			ID:         actor.ID,
+			ApproveAny: actor.Can(constant.ItemsApprovePermission),
+		},
+	}
+	if !mayApproveAsOther(item, actor) || !standing.mayApprove {
+		return auth
+	}
+	auth.decider.Position = standing.position
+
+	// An escalation may have handed the item to a specific approver
+	if item.AssigneeID != nil && *item.AssigneeID == actor.ID {
+		auth.roles = append(auth.roles, constant.ItemApproverActor)
+		auth.decider.ApproveAny = true
+		return auth
+	}
+
+	if hasStep && auth.decider.CanDecide(step) {
+		auth.roles = append(auth.roles, constant.ItemApproverActor)
+		return auth
+	}
+
+	for _, d := range standing.delegations {
+		if !d.Covers(item.Total()) {
+			continue
+		}
+		decider := approval.Decider{
+			ID:         actor.ID,
+			Position:   d.DelegatorPosition,
+			ApproveAny: d.DelegatorCanApprove,
+			OnBehalfOf: &d.DelegatorID,
+		}
+		if decider.ApproveAny || (hasStep && decider.CanDecide(step)) {
+			auth.roles = append(auth.roles, constant.ItemApproverActor)
+			auth.decider = decider
+			return auth
+		}
+	}
+	return auth
+}
+
+// changeStatus moves item to status on behalf of actor. Approvals and
+// rejections go through the item's approval chain, so an approval only
+// moves the item to APPROVED once the last step is approved.
+func (service Service) changeStatus(item model.Item, status constant.ItemStatus, reason string, actor Actor) (model.Item, error) {
+	if status == constant.ItemRejectedStatus && strings.TrimSpace(reason) == "" {
+		return model.Item{}, ErrReasonRequired
+	}
+
+	// Check lifecycle
+	auth, err := service.authority(item, actor)
+	if err != nil {
+		return model.Item{}, err
+	}
+	if err := Authorize(ActionChangeStatus, actor, auth.roles); err != nil {
+		return model.Item{}, err
+	}
+	if err := checkTransition(item, status, auth.roles); err != nil {
+		return model.Item{}, err
+	}
+	entry := model.ItemHistory{
+		ItemID:       item.ID,
+		Reason:       reason,
+		ActorID:      actor.ID,
+		OnBehalfOfID: auth.decider.OnBehalfOf,
+	}
+
+	// Approval chain
+	switch status {
+	case constant.ItemApprovedStatus, constant.ItemRejectedStatus:
+		outcome, step, err := service.Approval.Decide(item, status == constant.ItemApprovedStatus, auth.decider)
+		if err != nil {
+			return model.Item{}, err
+		}
+		if step.ID != 0 {
+			stepValues := map[string]any{"level": step.Level, "name": step.Name, "status": step.Status}
+			entry.Action = constant.ItemApprovalStepAction
+			if err := service.record(entry, nil, stepValues); err != nil {
+				return model.Item{}, err
+			}
+		}
+		if outcome == approval.OutcomeInProgress {
+			status = constant.ItemPendingStatus
+		}
+	case constant.ItemSubmittedStatus:
+		if _, err := service.Approval.Start(item); err != nil {
+			return model.Item{}, err
+		}
+	case constant.ItemDraftStatus, constant.ItemCancelledStatus:
+		if err := service.Approval.Cancel(item.ID); err != nil {
+			return model.Item{}, err
+		}
+	}
+
+	if status == item.Status {
+		return item, nil
+	}
+
+	// Update
+	updated, err := service.Repository.UpdateStatus(item.ID, item.Status, status)
+	if err != nil {
+		return model.Item{}, err
+	}
+	if !updated {
+		return model.Item{}, ErrStatusChanged
+	}
+
+	before := map[string]any{"status": item.Status}
+	after := map[string]any{"status": status}
+	entry.Action = constant.ItemStatusAction
+	if err := service.record(entry, before, after); err != nil {
 		return model.Item{}, err
 	}
 
-	// Fill data
 	item.Status = status
 
-	// Replace
-	if err := service.Repository.Replace(item); err != nil {
+	// Keep what was submitted and what was rejected
+	if status == constant.ItemSubmittedStatus || status == constant.ItemRejectedStatus {
+		if err := service.snapshot(item, actor.ID); err != nil {
+			return model.Item{}, err
+		}
+	}
+	return service.applyRules(item)
+}
+
+// applyRules runs the admin defined rules against an item waiting for
+// approval: the first matching auto-approve rule approves it, routing rules
+// add an approval step for their approver position.
+func (service Service) applyRules(item model.Item) (model.Item, error) {
+	if !awaitingApproval(item) {
+		return item, nil
+	}
+	rules, err := service.Rules.Match(item)
+	if err != nil {
 		return model.Item{}, err
 	}
 
+	for _, r := range rules {
+		switch r.Action {
+		case constant.RuleAutoApproveAction:
+			if err := service.Approval.Cancel(item.ID); err != nil {
+				return model.Item{}, err
+			}
+			updated, err := service.Repository.UpdateStatus(item.ID, item.Status, constant.ItemApprovedStatus)
+			if err != nil {
+				return model.Item{}, err
+			}
+			if !updated {
+				return model.Item{}, ErrStatusChanged
+			}
+
+			entry := model.ItemHistory{
+				ItemID: item.ID,
+				Action: constant.ItemStatusAction,
+				Reason: fmt.Sprintf("auto-approved by rule %d (%s)", r.ID, r.Name),
+			}
+			before := map[string]any{"status": item.Status}
+			after := map[string]any{"status": constant.ItemApprovedStatus}
+			if err := service.record(entry, before, after); err != nil {
+				return model.Item{}, err
+			}
+
+			item.Status = constant.ItemApprovedStatus
+			return item, nil
+		case constant.RuleRouteAction:
+			name := fmt.Sprintf("Rule: %s", r.Name)
+			if err := service.Approval.Route(item, r.RouteTo, name); err != nil {
+				return model.Item{}, err
+			}
+		}
+	}
+	return item, nil
+}
+
+// Resubmit applies the requester's changes to a rejected item and puts it
+// up for approval again.
+func (service Service) Resubmit(id uint, req model.RequestResubmitItem, actor Actor) (model.Item, error) {
+	var item model.Item
+	err := service.transaction(func(service Service) error {
+		// Find item
+		found, err := service.find(id, actor, ActionUpdate)
+		if err != nil {
+			return err
+		}
+		if found.Status != constant.ItemRejectedStatus {
+			return ErrNotRejected
+		}
+
+		// Check lifecycle before touching the item
+		auth, err := service.authority(found, actor)
+		if err != nil {
+			return err
+		}
+		if err := checkTransition(found, constant.ItemSubmittedStatus, auth.roles); err != nil {
+			return err
+		}
+
+		edited, err := service.applyChanges(found, req.RequestUpdateItem, constant.ItemUpdatedAction, req.Reason, actor)
+		if err != nil {
+			return err
+		}
+
+		item, err = service.changeStatus(edited, constant.ItemSubmittedStatus, req.Reason, actor)
+		return err
+	})
+	if err != nil {
+		return model.Item{}, err
+	}
 	return item, nil
 }
 
-func (service Service) Delete(id uint) error {
-	return service.Repository.Delete(id)
+// snapshot stores the item's current content as a new revision.
+func (service Service) snapshot(item model.Item, actorID int) error {
+	return service.Repository.CreateRevision(&model.ItemRevision{
+		ItemID:    item.ID,
+		Event:     item.Status,
+		Title:     item.Title,
+		Amount:    item.Amount,
+		Quantity:  item.Quantity,
+		CreatedBy: actorID,
+	})
 }
 
-func (service Service) UpdateManyStatus(ids []int, status string) error {
-	return service.Repository.UpdateManyStatus(ids, status)
+func (service Service) FindRevisions(id uint, actor Actor) ([]model.ItemRevision, error) {
+	if err := service.Authorize(id, actor, ActionView); err != nil {
+		return nil, err
+	}
+	return service.Repository.FindRevisions(id)
 }
 
-func (service Service) DeleteMany(ids []int, uid int, userPostion string) error {
-	
-	if userPostion == string(constant.Admin) {
-	return service.Repository.DeleteMany(ids)
+// DiffRevisions compares two revisions of an item field by field. A zero
+// from means the latest rejected revision and a zero to the latest revision.
+func (service Service) DiffRevisions(id uint, from int, to int, actor Actor) (model.ResponseRevisionDiff, error) {
+	revisions, err := service.FindRevisions(id, actor)
+	if err != nil {
+		return model.ResponseRevisionDiff{}, err
+	}
+	if len(revisions) == 0 {
+		return model.ResponseRevisionDiff{}, gorm.ErrRecordNotFound
 	}
 
-	return service.Repository.DeleteManyByUserId(ids, uid)
+	if to == 0 {
+		to = revisions[len(revisions)-1].Revision
+	}
+	if from == 0 {
+		for _, revision := range revisions {
+			if revision.Event == constant.ItemRejectedStatus && revision.Revision < to {
+				from = revision.Revision
+			}
+		}
+	}
+
+	var diff model.ResponseRevisionDiff
+	foundFrom, foundTo := false, false
+	for _, revision := range revisions {
+		if revision.Revision == from {
+			diff.From, foundFrom = revision, true
+		}
+		if revision.Revision == to {
+			diff.To, foundTo = revision, true
+		}
+	}
+	if !foundFrom || !foundTo {
+		return model.ResponseRevisionDiff{}, gorm.ErrRecordNotFound
+	}
+
+	diff.Changes = []model.FieldChange{}
+	fields := []struct {
+		name     string
+		from, to any
+	}{
+		{"title", diff.From.Title, diff.To.Title},
+		{"amount", diff.From.Amount, diff.To.Amount},
+		{"quantity", diff.From.Quantity, diff.To.Quantity},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			diff.Changes = append(diff.Changes, model.FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+	return diff, nil
+}
+
+func (service Service) Delete(id uint, reason string, actor Actor) error {
+	return service.transaction(func(service Service) error {
+		item, err := service.find(id, actor, ActionDelete)
+		if err != nil {
+			return err
+		}
+		if err := checkDeletable(item, actor); err != nil {
+			return err
+		}
+		return service.delete([]model.Item{item}, reason, actor)
+	})
+}
+
+// checkDeletable keeps requesters from deleting items that are no longer
+// open for changes, like UpdateItem does. Users allowed to delete or
+// override any item still may.
+func checkDeletable(item model.Item, actor Actor) error {
+	if item.Status.Editable() || actor.Can(constant.ItemsDeleteAnyPermission) || actor.Can(constant.ItemsOverridePermission) {
+		return nil
+	}
+	return ErrItemLocked
+}
+
+// delete removes items and records their last values in the history.
+func (service Service) delete(items []model.Item, reason string, actor Actor) error {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = int(item.ID)
+		entry := model.ItemHistory{ItemID: item.ID, Action: constant.ItemDeletedAction, Reason: reason, ActorID: actor.ID}
+		if err := service.record(entry, itemValues(item), nil); err != nil {
+			return err
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return service.Repository.DeleteMany(ids)
+}
+
+func (service Service) UpdateManyStatus(ids []int, status constant.ItemStatus, reason string, actor Actor) error {
+	// Ids given twice change the item once
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	return service.transaction(func(service Service) error {
+		items, err := service.Repository.FindByIDs(ids)
+		if err != nil {
+			return err
+		}
+		if len(items) != len(ids) {
+			return gorm.ErrRecordNotFound
+		}
+
+		// A failure on any item rolls back the whole batch
+		for _, item := range items {
+			if _, err := service.changeStatus(item, status, reason, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (service Service) DeleteMany(ids []int, reason string, actor Actor) error {
+	return service.transaction(func(service Service) error {
+		// Users allowed to delete any item may, other users only their own
+		var (
+			items []model.Item
+			err   error
+		)
+		if actor.Can(constant.ItemsDeleteAnyPermission) {
+			items, err = service.Repository.FindByIDs(ids)
+		} else {
+			items, err = service.Repository.FindByIDsAndOwner(ids, actor.ID)
+		}
+		if err != nil {
+			return err
+		}
+		// A locked item fails the whole batch
+		for _, item := range items {
+			if err := checkDeletable(item, actor); err != nil {
+				return err
+			}
+		}
+
+		return service.delete(items, reason, actor)
+	})
 }
+
 func (service Service) CountItemsStatusByUser(ownerID int) (map[string]int, error) {
 	return service.Repository.CountItemsStatusByUser(ownerID)
-}
\ No newline at end of file
+}
+
+// FindHistory returns the history of an item. Users allowed to read any item
+// can also read the history of deleted items.
+func (service Service) FindHistory(id uint, actor Actor) ([]model.ItemHistory, error) {
+	err := service.Authorize(id, actor, ActionView)
+	if errors.Is(err, gorm.ErrRecordNotFound) && actor.Can(constant.ItemsReadAnyPermission) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return service.Repository.FindHistory(id)
+}
+
+func (service Service) FindApprovalSteps(id uint, actor Actor) ([]model.ApprovalStep, error) {
+	if err := service.Authorize(id, actor, ActionView); err != nil {
+		return nil, err
+	}
+	return service.Approval.Steps(id)
+}
+
+// record appends entry to the item's history with the given old and new values.
+func (service Service) record(entry model.ItemHistory, before, after map[string]any) error {
+	history := entry
+	if before != nil {
+		value, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		history.OldValue = model.JSONText(value)
+	}
+	if after != nil {
+		value, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+		history.NewValue = model.JSONText(value)
+	}
+	return service.Repository.CreateHistory(&history)
+}
+
+// itemValues returns the fields of an item that are kept in its history.
+func itemValues(item model.Item) map[string]any {
+	return map[string]any{
+		"title":    item.Title,
+		"amount":   item.Amount,
+		"quantity": item.Quantity,
+		"status":   item.Status,
+		"owner_id": item.OwnerID,
+	}
+}
+
+// changedValues keeps only the fields whose value differs between before and after.
+func changedValues(before, after map[string]any) (map[string]any, map[string]any) {
+	beforeChanged, afterChanged := map[string]any{}, map[string]any{}
+	for key, value := range after {
+		if before[key] != value {
+			beforeChanged[key] = before[key]
+			afterChanged[key] = value
+		}
+	}
+	return beforeChanged, afterChanged
+}
@@ -0,0 +1,144 @@
+package item
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+)
+
+func TestResolveAuthority(t *testing.T) {
+	const (
+		ownerID    = 1
+		approverID = 2
+	)
+	maxAmount := 100
+	assigneeID, ownerAssignee := approverID, ownerID
+	pending := model.Item{ID: 1, OwnerID: ownerID, Status: constant.ItemPendingStatus, Amount: 50, Quantity: 1}
+	step := model.ApprovalStep{ItemID: 1, ApproverPosition: "Manager", Status: constant.ApprovalStepPendingStatus}
+	managerDelegation := model.Delegation{DelegatorID: 3, DelegatorPosition: "Manager"}
+	smallDelegation := model.Delegation{DelegatorID: 3, DelegatorPosition: "Manager", MaxAmount: &maxAmount}
+
+	tests := []struct {
+		name         string
+		item         model.Item
+		actor        Actor
+		standing     standing
+		wantApprover bool
+		wantOnBehalf bool
+	}{
+		{
+			name:         "step approver approves",
+			item:         pending,
+			actor:        Actor{ID: approverID},
+			standing:     standing{mayApprove: true, position: "Manager"},
+			wantApprover: true,
+		},
+		{
+			name:     "other positions do not approve",
+			item:     pending,
+			actor:    Actor{ID: approverID},
+			standing: standing{mayApprove: true, position: "Clerk"},
+		},
+		{
+			name:     "owner holding the step's position does not approve",
+			item:     pending,
+			actor:    Actor{ID: ownerID},
+			standing: standing{mayApprove: true, position: "Manager"},
+		},
+		{
+			name:     "owner does not approve under a delegation",
+			item:     pending,
+			actor:    Actor{ID: ownerID},
+			standing: standing{mayApprove: true, delegations: []model.Delegation{managerDelegation}},
+		},
+		{
+			name:         "delegate approves on behalf of the delegator",
+			item:         pending,
+			actor:        Actor{ID: approverID},
+			standing:     standing{mayApprove: true, position: "Clerk", delegations: []model.Delegation{managerDelegation}},
+			wantApprover: true,
+			wantOnBehalf: true,
+		},
+		{
+			name:     "delegation below the item total does not approve",
+			item:     model.Item{ID: 1, OwnerID: ownerID, Status: constant.ItemPendingStatus, Amount: 50, Quantity: 3},
+			actor:    Actor{ID: approverID},
+			standing: standing{mayApprove: true, delegations: []model.Delegation{smallDelegation}},
+		},
+		{
+			name:         "escalation assignee approves",
+			item:         model.Item{ID: 1, OwnerID: ownerID, Status: constant.ItemPendingStatus, AssigneeID: &assigneeID},
+			actor:        Actor{ID: approverID},
+			standing:     standing{mayApprove: true},
+			wantApprover: true,
+		},
+		{
+			name:     "owner assigned their own item does not approve",
+			item:     model.Item{ID: 1, OwnerID: ownerID, Status: constant.ItemPendingStatus, AssigneeID: &ownerAssignee},
+			actor:    Actor{ID: ownerID},
+			standing: standing{mayApprove: true},
+		},
+		{
+			name:     "step approver without required two-factor does not approve",
+			item:     pending,
+			actor:    Actor{ID: approverID},
+			standing: standing{position: "Manager"},
+		},
+		{
+			name:  "assignee without required two-factor does not approve",
+			item:  model.Item{ID: 1, OwnerID: ownerID, Status: constant.ItemPendingStatus, AssigneeID: &assigneeID},
+			actor: Actor{ID: approverID},
+		},
+		{
+			name:     "closed items have no step approver",
+			item:     model.Item{ID: 1, OwnerID: ownerID, Status: constant.ItemApprovedStatus},
+			actor:    Actor{ID: approverID},
+			standing: standing{mayApprove: true, position: "Manager"},
+		},
+		{
+			name:         "approve permission approves",
+			item:         pending,
+			actor:        Actor{ID: approverID, Permissions: []string{string(constant.ItemsApprovePermission)}},
+			wantApprover: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := resolveAuthority(tt.item, tt.actor, tt.standing, step, true)
+			if isApprover := slices.Contains(auth.roles, constant.ItemApproverActor); isApprover != tt.wantApprover {
+				t.Fatalf("approver = %v, want %v (roles %v)", isApprover, tt.wantApprover, auth.roles)
+			}
+			if onBehalf := auth.decider.OnBehalfOf != nil; onBehalf != tt.wantOnBehalf {
+				t.Fatalf("deciding on behalf of someone = %v, want %v", onBehalf, tt.wantOnBehalf)
+			}
+		})
+	}
+}
+
+func TestCheckDeletable(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  constant.ItemStatus
+		actor   Actor
+		wantErr error
+	}{
+		{"owner deletes a draft", constant.ItemDraftStatus, Actor{ID: 1}, nil},
+		{"owner deletes a pending item", constant.ItemPendingStatus, Actor{ID: 1}, nil},
+		{"owner cannot delete an approved item", constant.ItemApprovedStatus, Actor{ID: 1}, ErrItemLocked},
+		{"owner cannot delete a rejected item", constant.ItemRejectedStatus, Actor{ID: 1}, ErrItemLocked},
+		{"delete any deletes an approved item", constant.ItemApprovedStatus, Actor{ID: 1, Permissions: []string{string(constant.ItemsDeleteAnyPermission)}}, nil},
+		{"override deletes an approved item", constant.ItemApprovedStatus, Actor{ID: 1, Permissions: []string{string(constant.ItemsOverridePermission)}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := model.Item{ID: 1, OwnerID: 1, Status: tt.status}
+			if err := checkDeletable(item, tt.actor); err != tt.wantErr {
+				t.Fatalf("checkDeletable() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package lockout
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB, config Config) Controller {
+	return Controller{
+		Service: NewService(db, config),
+	}
+}
+
+func (controller Controller) FindLockouts(ctx *gin.Context) {
+	// Bind
+	var request model.RequestFindLockouts
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	lockouts, err := controller.Service.FindAll(request)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": lockouts,
+	})
+}
+
+func (controller Controller) ClearLockout(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	if err := controller.Service.Clear(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"message": "Lockout not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Cleared",
+	})
+}
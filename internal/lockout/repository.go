@@ -0,0 +1,88 @@
+package lockout
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+// FindLocked returns the lockout of a subject when it lasts beyond now.
+func (repo Repository) FindLocked(kind constant.LockoutKind, subject string, now time.Time) (model.Lockout, bool, error) {
+	var results []model.Lockout
+	err := repo.Database.
+		Where("kind = ? AND subject = ? AND locked_until > ?", kind, subject, now).
+		Limit(1).
+		Find(&results).Error
+	if err != nil || len(results) == 0 {
+		return model.Lockout{}, false, err
+	}
+	return results[0], true, nil
+}
+
+// AddFailure counts a failed login of a subject and returns its lockout.
+// Failures before forgetBefore no longer count.
+func (repo Repository) AddFailure(kind constant.LockoutKind, subject string, now time.Time, forgetBefore time.Time) (model.Lockout, error) {
+	lockout := model.Lockout{
+		Kind:          kind,
+		Subject:       subject,
+		Failures:      1,
+		LastFailureAt: now,
+	}
+	err := repo.Database.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "kind"}, {Name: "subject"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN lockouts.last_failure_at < ? THEN 1 ELSE lockouts.failures + 1 END", forgetBefore),
+				"last_failure_at": now,
+			}),
+		},
+		clause.Returning{},
+	).Create(&lockout).Error
+	return lockout, err
+}
+
+func (repo Repository) SetLockedUntil(id uint, lockedUntil *time.Time) error {
+	return repo.Database.Model(&model.Lockout{}).Where("id = ?", id).Update("locked_until", lockedUntil).Error
+}
+
+func (repo Repository) DeleteSubject(kind constant.LockoutKind, subject string) error {
+	return repo.Database.Where("kind = ? AND subject = ?", kind, subject).Delete(&model.Lockout{}).Error
+}
+
+func (repo Repository) FindAll(lockedAfter *time.Time) ([]model.Lockout, error) {
+	var results []model.Lockout
+	db := repo.Database
+	if lockedAfter != nil {
+		db = db.Where("locked_until > ?", *lockedAfter)
+	}
+	if err := db.Order("last_failure_at DESC").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// Delete removes a lockout, reporting gorm.ErrRecordNotFound when there is none.
+func (repo Repository) Delete(id uint) error {
+	result := repo.Database.Delete(&model.Lockout{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
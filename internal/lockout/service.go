@@ -0,0 +1,204 @@
+package lockout
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Config decides when failed logins lock an account or an IP address.
+type Config struct {
+	// AccountThreshold is the number of failures that locks an account.
+	AccountThreshold int
+	// IPThreshold is the number of failures that locks an IP address. It is
+	// higher than AccountThreshold since offices share addresses.
+	IPThreshold int
+	// BaseDuration is the first lock; every further failure doubles it.
+	BaseDuration time.Duration
+	// MaxDuration caps the lock.
+	MaxDuration time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+// ConfigFromEnv reads the lockout configuration from LOCKOUT_ACCOUNT_THRESHOLD,
+// LOCKOUT_IP_THRESHOLD, LOCKOUT_BASE_DURATION, LOCKOUT_MAX_DURATION and
+// LOCKOUT_WINDOW.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		AccountThreshold: 5,
+		IPThreshold:      20,
+		BaseDuration:     time.Minute,
+		MaxDuration:      time.Hour,
+		Window:           24 * time.Hour,
+	}
+
+	for name, threshold := range map[string]*int{
+		"LOCKOUT_ACCOUNT_THRESHOLD": &config.AccountThreshold,
+		"LOCKOUT_IP_THRESHOLD":      &config.IPThreshold,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				return config, fmt.Errorf("%s: must be a positive number", name)
+			}
+			*threshold = parsed
+		}
+	}
+	for name, duration := range map[string]*time.Duration{
+		"LOCKOUT_BASE_DURATION": &config.BaseDuration,
+		"LOCKOUT_MAX_DURATION":  &config.MaxDuration,
+		"LOCKOUT_WINDOW":        &config.Window,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return config, fmt.Errorf("%s: %w", name, err)
+			}
+			// A zero duration would turn lockouts off
+			if parsed <= 0 {
+				log.Printf("Invalid %s %q, using the default\n", name, value)
+				continue
+			}
+			*duration = parsed
+		}
+	}
+	if config.MaxDuration < config.BaseDuration {
+		log.Printf("LOCKOUT_MAX_DURATION %s is below LOCKOUT_BASE_DURATION %s, using the defaults\n", config.MaxDuration, config.BaseDuration)
+		config.BaseDuration, config.MaxDuration = time.Minute, time.Hour
+	}
+
+	return config, nil
+}
+
+// backoff is how long a subject with the given failures is locked: nothing
+// below the threshold, then BaseDuration doubling with every failure up to
+// MaxDuration.
+func (config Config) backoff(failures int, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	duration := config.BaseDuration
+	for i := threshold; i < failures && duration < config.MaxDuration; i++ {
+		duration *= 2
+	}
+	return min(duration, config.MaxDuration)
+}
+
+func (config Config) threshold(kind constant.LockoutKind) int {
+	if kind == constant.IPLockout {
+		return config.IPThreshold
+	}
+	return config.AccountThreshold
+}
+
+// LockedError is returned for logins of a locked account or from a locked
+// IP address.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e LockedError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
+type Service struct {
+	Repository Repository
+	Config     Config
+}
+
+func NewService(db *gorm.DB, config Config) Service {
+	return Service{
+		Repository: NewRepository(db),
+		Config:     config,
+	}
+}
+
+// Check returns a LockedError when username or ip is locked. Usernames are
+// counted whether or not the account exists, so lockouts do not tell them apart.
+func (service Service) Check(username string, ip string) error {
+	now := time.Now()
+	var lockedUntil time.Time
+	for _, subject := range subjects(username, ip) {
+		lockout, ok, err := service.Repository.FindLocked(subject.kind, subject.name, now)
+		if err != nil {
+			return err
+		}
+		if ok && lockout.LockedUntil.After(lockedUntil) {
+			lockedUntil = *lockout.LockedUntil
+		}
+	}
+	if lockedUntil.IsZero() {
+		return nil
+	}
+	return LockedError{RetryAfter: lockedUntil.Sub(now)}
+}
+
+// RecordFailure counts a failed login for username and ip, locking them
+// once they reach their threshold.
+func (service Service) RecordFailure(username string, ip string) error {
+	now := time.Now()
+	return service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		for _, subject := range subjects(username, ip) {
+			lockout, err := repo.AddFailure(subject.kind, subject.name, now, now.Add(-service.Config.Window))
+			if err != nil {
+				return err
+			}
+
+			var lockedUntil *time.Time
+			if backoff := service.Config.backoff(lockout.Failures, service.Config.threshold(subject.kind)); backoff > 0 {
+				until := now.Add(backoff)
+				lockedUntil = &until
+			}
+			if err := repo.SetLockedUntil(lockout.ID, lockedUntil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RecordSuccess forgets the failures of an account after a successful
+// login. Failures of the IP address still count, so one known password does
+// not help guessing others.
+func (service Service) RecordSuccess(username string) error {
+	return service.Repository.DeleteSubject(constant.AccountLockout, username)
+}
+
+// FindAll lists the accounts and addresses with recent failures, or only
+// the locked ones.
+func (service Service) FindAll(req model.RequestFindLockouts) ([]model.Lockout, error) {
+	if req.Locked {
+		now := time.Now()
+		return service.Repository.FindAll(&now)
+	}
+	return service.Repository.FindAll(nil)
+}
+
+// Clear lifts a lockout and forgets its failures.
+func (service Service) Clear(id uint) error {
+	return service.Repository.Delete(id)
+}
+
+type subject struct {
+	kind constant.LockoutKind
+	name string
+}
+
+// subjects lists what a login attempt counts against, always in the same
+// order so concurrent failures lock the rows in the same order.
+func subjects(username string, ip string) []subject {
+	subjects := []subject{{kind: constant.AccountLockout, name: username}}
+	if ip != "" {
+		subjects = append(subjects, subject{kind: constant.IPLockout, name: ip})
+	}
+	return subjects
+}
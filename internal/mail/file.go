@@ -0,0 +1,22 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSender writes each email to an .eml file in Dir instead of sending it.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func (sender FileSender) Send(message Message) error {
+	if err := os.MkdirAll(sender.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.eml", time.Now().Format("20060102T150405.000000000"))
+	return os.WriteFile(filepath.Join(sender.Dir, name), format(sender.From, message), 0o600)
+}
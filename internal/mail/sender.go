@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails.
+type Sender interface {
+	Send(message Message) error
+}
+
+// LogSender writes emails to the application log.
+type LogSender struct{}
+
+func (LogSender) Send(message Message) error {
+	log.Printf("Mail to %s: %s\n%s\n", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// SenderFromEnv builds the sender selected by MAIL_DRIVER:
+//   - "log" (default) writes emails to the application log
+//   - "file" writes each email to a file in MAIL_DIR, for local development and tests
+//   - "smtp" sends through SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD
+//
+// MAIL_FROM is the sender address.
+func SenderFromEnv() (Sender, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "workflow@localhost"
+	}
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "", "log":
+		return LogSender{}, nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return FileSender{Dir: dir, From: from}, nil
+	case "smtp":
+		port := 587
+		if value := os.Getenv("SMTP_PORT"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("SMTP_PORT: %w", err)
+			}
+			port = parsed
+		}
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is not set")
+		}
+		return SMTPSender{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER: unknown driver %q", driver)
+	}
+}
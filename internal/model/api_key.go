@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
+
+// APIKey lets scripts call the API as their owner, limited to Scopes. Only
+// the key's hash is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         uint                   `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint                   `gorm:"not null;index" json:"user_id"`
+	Name       string                 `gorm:"size:100;not null" json:"name"`
+	Prefix     string                 `gorm:"size:20;not null" json:"prefix"`
+	KeyHash    string                 `gorm:"size:64;unique;not null" json:"-"`
+	Scopes     []constant.APIKeyScope `gorm:"type:text;serializer:json;not null" json:"scopes"`
+	ExpiresAt  time.Time              `gorm:"not null" json:"expires_at"`
+	LastUsedAt *time.Time             `json:"last_used_at"`
+	RevokedAt  *time.Time             `json:"revoked_at"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// ResponseCreateAPIKey is returned once, when the key is created; the key
+// itself cannot be retrieved again.
+type ResponseCreateAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
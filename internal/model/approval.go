@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
+
+// ApprovalLevel is one level of the approval chain. Items whose total
+// exceeds MinTotal need an approval from ApproverPosition at this level.
+type ApprovalLevel struct {
+	ID               uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Level            int    `gorm:"not null;unique" json:"level"`
+	Name             string `gorm:"size:100;not null" json:"name"`
+	ApproverPosition string `gorm:"size:100;not null" json:"approver_position"`
+	MinTotal         int    `gorm:"not null" json:"min_total"`
+}
+
+// ApprovalStep is one approval an item needs, in the order given by Level.
+type ApprovalStep struct {
+	ID                uint                        `gorm:"primaryKey;autoIncrement" json:"id"`
+	ItemID            uint                        `gorm:"not null;index" json:"item_id"`
+	Round             int                         `gorm:"not null" json:"round"`
+	Level             int                         `gorm:"not null" json:"level"`
+	Name              string                      `gorm:"size:100;not null" json:"name"`
+	ApproverPosition  string                      `gorm:"size:100;not null" json:"approver_position"`
+	Status            constant.ApprovalStepStatus `gorm:"size:20;not null" json:"status"`
+	DecidedBy         *int                        `json:"decided_by"`
+	DecidedOnBehalfOf *int                        `json:"decided_on_behalf_of"`
+	DecidedAt         *time.Time                  `json:"decided_at"`
+	CreatedAt         time.Time                   `json:"created_at"`
+}
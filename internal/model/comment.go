@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type Comment struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ItemID    uint      `gorm:"not null;index" json:"item_id"`
+	AuthorID  int       `gorm:"not null" json:"author_id"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Delegation lets Delegate act as an approver for Delegator between StartsAt
+// and EndsAt, optionally only for items whose total is at most MaxAmount.
+type Delegation struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	DelegatorID int        `gorm:"not null;index" json:"delegator_id"`
+	DelegateID  int        `gorm:"not null;index" json:"delegate_id"`
+	StartsAt    time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt      time.Time  `gorm:"not null" json:"ends_at"`
+	MaxAmount   *int       `json:"max_amount"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// DelegatorPosition and DelegatorCanApprove describe the delegator's
+	// current position and approval rights, filled when looking up active
+	// delegations.
+	DelegatorPosition   string `gorm:"->;-:migration" json:"delegator_position,omitempty"`
+	DelegatorCanApprove bool   `gorm:"->;-:migration" json:"delegator_can_approve,omitempty"`
+}
+
+// Covers reports whether the delegation applies to a request of the given total.
+func (d Delegation) Covers(total int) bool {
+	return d.MaxAmount == nil || total <= *d.MaxAmount
+}
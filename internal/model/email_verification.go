@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// EmailVerification is a single-use, time-limited token mailed to a user to
+// prove they read Email. Only its hash is stored.
+type EmailVerification struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Email     string     `gorm:"type:text;not null" json:"email"`
+	TokenHash string     `gorm:"size:64;unique;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
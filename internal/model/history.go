@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
+
+// JSONText is a JSON document stored in a text column.
+type JSONText string
+
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
+
+// ItemHistory is an append-only record of a change made to an item.
+type ItemHistory struct {
+	ID       uint                       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ItemID   uint                       `gorm:"not null;index" json:"item_id"`
+	Action   constant.ItemHistoryAction `gorm:"size:20;not null" json:"action"`
+	OldValue JSONText                   `gorm:"type:text" json:"old_value"`
+	NewValue JSONText                   `gorm:"type:text" json:"new_value"`
+	Reason   string                     `gorm:"type:text" json:"reason"`
+	ActorID  int                        `gorm:"not null" json:"actor_id"`
+	// OnBehalfOfID is the approver the actor stood in for under a delegation.
+	OnBehalfOfID *int      `json:"on_behalf_of_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
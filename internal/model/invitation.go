@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Invitation is a single-use, time-limited token an admin hands out to let
+// someone register with a given role and position. Only its hash is stored.
+type Invitation struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	TokenHash  string     `gorm:"size:64;unique;not null" json:"-"`
+	Email      string     `json:"email"`
+	RoleID     uint       `gorm:"not null" json:"role_id"`
+	Role       Role       `json:"role"`
+	Position   string     `json:"position"`
+	CreatedBy  uint       `gorm:"not null" json:"created_by"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	AcceptedBy *uint      `json:"accepted_by"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Status     string     `gorm:"-" json:"status"`
+}
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// StatusAt returns the status of the invitation at time now.
+func (i Invitation) StatusAt(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !i.ExpiresAt.After(now):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
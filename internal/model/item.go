@@ -1,6 +1,10 @@
 package model
 
-import "github.com/Kiratopat-s/workflow/internal/constant"
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
 
 type Item struct {
 	ID       uint           `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -9,5 +13,15 @@ type Item struct {
     Quantity int            `gorm:"not null" json:"quantity"`
     Status   constant.ItemStatus         `gorm:"size:20;not null" json:"status"`
     OwnerID  int            `gorm:"not null" json:"owner_id"`
+    // StatusChangedAt is when the item entered its current status.
+    StatusChangedAt time.Time  `gorm:"not null" json:"status_changed_at"`
+    // EscalatedAt is set when the item waited for approval longer than the SLA.
+    EscalatedAt     *time.Time `json:"escalated_at"`
+    // AssigneeID is the approver an escalation reassigned the item to.
+    AssigneeID      *int       `json:"assignee_id"`
 }
 
+// Total is the full cost of the request.
+func (item Item) Total() int {
+	return item.Amount * item.Quantity
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
+
+// Lockout counts the recent failed logins of an account (by username) or
+// of an IP address. Once there are too many, logins are refused until
+// LockedUntil.
+type Lockout struct {
+	ID            uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind          constant.LockoutKind `gorm:"size:20;not null;uniqueIndex:idx_lockouts_subject" json:"kind"`
+	Subject       string               `gorm:"size:255;not null;uniqueIndex:idx_lockouts_subject" json:"subject"`
+	Failures      int                  `gorm:"not null" json:"failures"`
+	LastFailureAt time.Time            `gorm:"not null" json:"last_failure_at"`
+	LockedUntil   *time.Time           `json:"locked_until"`
+}
+
+type RequestFindLockouts struct {
+	// Locked limits the list to subjects that are locked right now.
+	Locked bool `form:"locked"`
+}
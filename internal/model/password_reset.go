@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// PasswordReset is a single-use, time-limited token sent to a user who
+// forgot their password. Only its hash is stored.
+type PasswordReset struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;unique;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -1,12 +1,17 @@
 package model
 
-import "github.com/Kiratopat-s/workflow/internal/constant"
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
 
 // Request to create a new item
 type RequestCreateItem struct {
     Title    string  `json:"title" binding:"required"`
     Amount   int     `json:"amount" binding:"required"`
     Quantity int     `json:"quantity" binding:"required"`
+    Draft    bool    `json:"draft"`
 }
 
 // Request to update an existing item
@@ -14,11 +19,13 @@ type RequestUpdateItem struct {
     Title    *string `json:"title"`
     Amount   *int    `json:"amount"`
     Quantity *int    `json:"quantity"`
+    Reason   string  `json:"reason"`
 }
 
 // Request to change item status
 type RequestPatchItemStatus struct {
     Status constant.ItemStatus `json:"status" binding:"required"`
+    Reason string              `json:"reason" binding:"required_if=Status REJECTED"`
 }
 
 // Request to find items by status
@@ -68,10 +75,224 @@ type ResponseLogout struct {
 }
 
 type RequestPatchManyItemStatus struct {
-	IDs    []int    `json:"ids" binding:"required"`
-	Status string `json:"status" binding:"required"`
+	IDs    []int               `json:"ids" binding:"required"`
+	Status constant.ItemStatus `json:"status" binding:"required"`
+	Reason string              `json:"reason" binding:"required_if=Status REJECTED"`
 }
 
 type RequestDeleteManyItems struct {
-    IDs []int `json:"ids" binding:"required"`
-}
\ No newline at end of file
+    IDs    []int  `json:"ids" binding:"required"`
+    Reason string `json:"reason"`
+}
+
+// Request to replace the approval chain levels
+type RequestPutApprovalLevels struct {
+	Levels []RequestApprovalLevel `json:"levels" binding:"required,dive"`
+}
+
+type RequestApprovalLevel struct {
+	Level            int    `json:"level" binding:"required,gt=0"`
+	Name             string `json:"name" binding:"required"`
+	ApproverPosition string `json:"approver_position" binding:"required"`
+	MinTotal         int    `json:"min_total" binding:"gte=0"`
+}
+
+// Request to write or edit a comment on an item
+type RequestComment struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// Request to delegate approvals to another user
+type RequestCreateDelegation struct {
+	DelegateID int       `json:"delegate_id" binding:"required"`
+	StartsAt   time.Time `json:"starts_at" binding:"required"`
+	EndsAt     time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	MaxAmount  *int      `json:"max_amount" binding:"omitempty,gt=0"`
+}
+
+// Request to resubmit a rejected item, optionally with changes
+type RequestResubmitItem struct {
+	RequestUpdateItem
+}
+
+// Request for an admin to change an item regardless of its status
+type RequestOverrideItem struct {
+	Title         *string `json:"title"`
+	Amount        *int    `json:"amount"`
+	Quantity      *int    `json:"quantity"`
+	Justification string  `json:"justification" binding:"required"`
+}
+
+// Request to compare two revisions of an item
+type RequestRevisionDiff struct {
+	From int `form:"from" binding:"gte=0"`
+	To   int `form:"to" binding:"gte=0"`
+}
+
+// Request to create or replace an auto-approval or routing rule
+type RequestRule struct {
+	Name       string              `json:"name" binding:"required"`
+	Expression string              `json:"expression" binding:"required"`
+	Action     constant.RuleAction `json:"action" binding:"required,oneof=AUTO_APPROVE ROUTE"`
+	RouteTo    string              `json:"route_to" binding:"required_if=Action ROUTE"`
+	Priority   int                 `json:"priority"`
+	Enabled    *bool               `json:"enabled"`
+}
+
+// Request to evaluate a rule against a sample item without saving anything
+type RequestRuleDryRun struct {
+	RuleID     uint              `json:"rule_id"`
+	Expression string            `json:"expression" binding:"required_without=RuleID"`
+	Item       RequestSampleItem `json:"item"`
+	Requester  RequestSampleUser `json:"requester"`
+}
+
+type RequestSampleItem struct {
+	Title    string              `json:"title"`
+	Amount   int                 `json:"amount"`
+	Quantity int                 `json:"quantity"`
+	Status   constant.ItemStatus `json:"status"`
+	OwnerID  int                 `json:"owner_id"`
+}
+
+type RequestSampleUser struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	Position  string `json:"position"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// Request to replace the roles of a user
+type RequestSetUserRoles struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+// Request to exchange a refresh token for a new token pair
+type RequestRefreshToken struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Request to log out; the refresh token, when given, is revoked too
+type RequestLogout struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Request to revoke an access token by its jti
+type RequestRevokeToken struct {
+	JTI    string `json:"jti" binding:"required"`
+	UserID *int   `json:"user_id"`
+}
+
+// Response for login and token refresh
+type ResponseToken struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// Request to create an API key
+type RequestCreateAPIKey struct {
+	Name      string                 `json:"name" binding:"required,max=100"`
+	Scopes    []constant.APIKeyScope `json:"scopes" binding:"required,min=1,dive,oneof=items:read items:write"`
+	ExpiresAt *time.Time             `json:"expires_at"`
+}
+
+// Request carrying a TOTP code or a recovery code
+type RequestTwoFactorCode struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// Request to complete a login with the second factor
+type RequestLoginTwoFactor struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// Request to change the two-factor authentication settings
+type RequestTwoFactorSettings struct {
+	RequiredForApprovers *bool `json:"required_for_approvers" binding:"required"`
+}
+
+// Request to change the password of the logged in user
+type RequestChangePassword struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// Request a password reset link
+type RequestForgotPassword struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// Request to set a new password with a reset token
+type RequestResetPassword struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// Request to list users, a page at a time
+type RequestFindUsers struct {
+	Query    string `form:"q"`
+	Status   string `form:"status" binding:"omitempty,oneof=active deactivated"`
+	Role     string `form:"role"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// Request to change a user's position or roles
+type RequestUpdateUser struct {
+	Position *string   `json:"position"`
+	Roles    *[]string `json:"roles"`
+}
+
+// Pagination describes the page of a list response
+type Pagination struct {
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+	Total    int64 `json:"total"`
+}
+
+// Request to change the caller's own profile. Position and Username are
+// only there to be refused: admins change them.
+type RequestUpdateProfile struct {
+	FirstName *string `json:"first_name" binding:"omitempty,max=100"`
+	LastName  *string `json:"last_name" binding:"omitempty,max=100"`
+	PhotoLink *string `json:"photo_link" binding:"omitempty,max=2048"`
+	Email     *string `json:"email" binding:"omitnil,email,max=255"`
+	Position  *string `json:"position"`
+	Username  *string `json:"username"`
+}
+
+// Response for the caller's own profile
+type ResponseProfile struct {
+	User
+	Permissions []string `json:"permissions"`
+}
+
+// Request to invite someone to register
+type RequestCreateInvitation struct {
+	Role     string `json:"role" binding:"required"`
+	Position string `json:"position"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+// Request to list invitations
+type RequestFindInvitations struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending accepted revoked expired"`
+}
+
+// Response for a created invitation. The token is only ever shown here.
+type ResponseInvitation struct {
+	Invitation
+	Token  string `json:"token"`
+	URL    string `json:"url"`
+	Mailed bool   `json:"mailed"`
+}
+
+// Request to verify an email address with the mailed token
+type RequestVerifyEmail struct {
+	Token string `json:"token" binding:"required"`
+}
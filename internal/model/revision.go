@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
+
+// ItemRevision is an immutable snapshot of an item taken each time it is
+// submitted for approval or rejected.
+type ItemRevision struct {
+	ID        uint                `gorm:"primaryKey;autoIncrement" json:"id"`
+	ItemID    uint                `gorm:"not null;uniqueIndex:idx_item_revisions_item_revision" json:"item_id"`
+	Revision  int                 `gorm:"not null;uniqueIndex:idx_item_revisions_item_revision" json:"revision"`
+	Event     constant.ItemStatus `gorm:"size:20;not null" json:"event"`
+	Title     string              `gorm:"size:255;not null" json:"title"`
+	Amount    int                 `gorm:"not null" json:"amount"`
+	Quantity  int                 `gorm:"not null" json:"quantity"`
+	CreatedBy int                 `gorm:"not null" json:"created_by"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// FieldChange is one field that differs between two revisions.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type ResponseRevisionDiff struct {
+	From    ItemRevision  `json:"from"`
+	To      ItemRevision  `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
@@ -0,0 +1,19 @@
+package model
+
+type Permission struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string `gorm:"size:100;unique;not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+}
+
+type Role struct {
+	ID          uint         `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string       `gorm:"size:100;unique;not null" json:"name"`
+	Description string       `gorm:"type:text" json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+}
+
+type UserRole struct {
+	UserID uint `gorm:"primaryKey" json:"user_id"`
+	RoleID uint `gorm:"primaryKey" json:"role_id"`
+}
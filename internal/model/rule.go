@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+)
+
+// Rule is an admin defined CEL expression evaluated against items. When it
+// matches, its Action is applied to the item.
+type Rule struct {
+	ID         uint                `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name       string              `gorm:"size:255;not null" json:"name"`
+	Expression string              `gorm:"type:text;not null" json:"expression"`
+	Action     constant.RuleAction `gorm:"size:20;not null" json:"action"`
+	// RouteTo is the approver position items are routed to by ROUTE rules.
+	RouteTo   string    `gorm:"size:100" json:"route_to"`
+	Priority  int       `gorm:"not null" json:"priority"`
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+	CreatedBy int       `gorm:"not null" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Session is one login of a user. It lasts as long as the refresh tokens
+// rotated from that login, which share its FamilyID, and ends early when
+// the user or an admin signs it out.
+type Session struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID   string     `gorm:"size:64;unique;not null" json:"-"`
+	Device     string     `gorm:"size:100;not null" json:"device"`
+	UserAgent  string     `gorm:"type:text;not null" json:"user_agent"`
+	IP         string     `gorm:"column:ip;size:45;not null" json:"ip"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Current marks the caller's own session in listings.
+	Current bool `gorm:"-" json:"current"`
+}
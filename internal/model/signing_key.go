@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// SigningKey is an asymmetric key access tokens are signed with. A rotated
+// key is retired: it no longer signs, but still verifies the tokens it
+// signed until ExpiresAt.
+type SigningKey struct {
+	KID        string     `gorm:"column:kid;primaryKey;size:64" json:"kid"`
+	Algorithm  string     `gorm:"size:10;not null" json:"algorithm"`
+	PrivateKey string     `gorm:"type:text;not null" json:"-"`
+	PublicKey  string     `gorm:"type:text;not null" json:"public_key"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
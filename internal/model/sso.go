@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// UserIdentity links a user to their account at an OpenID Connect provider.
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Issuer      string     `gorm:"size:255;not null;uniqueIndex:idx_user_identities_subject" json:"issuer"`
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_user_identities_subject" json:"subject"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// SSOLoginState is a single sign-on login waiting for the provider to
+// redirect back. Only the state's hash is stored; the nonce and PKCE code
+// verifier are needed to finish the login.
+type SSOLoginState struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	StateHash    string    `gorm:"size:64;unique;not null"`
+	Nonce        string    `gorm:"size:64;not null"`
+	CodeVerifier string    `gorm:"size:128;not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+// RequestSSOCallback is the query the provider redirects back with.
+type RequestSSOCallback struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
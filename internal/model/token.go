@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// RefreshToken is a long-lived token exchanged for new access tokens. Only
+// its hash is stored. Tokens rotated from one login share a FamilyID.
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash    string     `gorm:"size:64;unique;not null" json:"-"`
+	FamilyID     string     `gorm:"size:64;not null;index" json:"family_id"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RevokedToken is an access token killed before it expires, by its jti.
+type RevokedToken struct {
+	JTI       string    `gorm:"column:jti;primaryKey;size:64" json:"jti"`
+	UserID    *int      `json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	RevokedAt time.Time `gorm:"not null" json:"revoked_at"`
+}
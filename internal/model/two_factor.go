@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the
+// authenticator is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginChallenge is a password login waiting for its second factor.
+type LoginChallenge struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	TokenHash string    `gorm:"size:64;unique;not null" json:"-"`
+	Attempts  int       `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Setting is a system setting changed by administrators at runtime.
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"type:text;not null" json:"value"`
+	UpdatedBy *int      `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoginResult is the outcome of a password login: a token pair, or a
+// challenge to complete with a second factor at /login/2fa.
+type LoginResult struct {
+	ResponseToken
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	// MFAEnrollmentRequired is set for approvers who must enroll before
+	// they can approve.
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+}
+
+// ResponseTwoFactorEnrollment is returned when starting enrollment.
+type ResponseTwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	// QRCodePNG is the otpauth URI as a base64 encoded PNG image.
+	QRCodePNG string `json:"qr_code_png"`
+}
+
+// ResponseTwoFactorStatus describes a user's two-factor authentication.
+type ResponseTwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
@@ -1,24 +1,57 @@
 package model
 
+import "time"
+
 type User struct {
 	ID        uint   `json:"id" gorm:"primaryKey;autoIncrement"`
 	Username  string `json:"username" gorm:"size:255;unique;not null"`
-	Password  string `json:"password" gorm:"size:255;not null"`
+	Password  string `json:"-" gorm:"size:255;not null"`
 	Position  string `json:"position" gorm:"size:100"`
 	FirstName string `json:"first_name" gorm:"size:100"`
 	LastName  string `json:"last_name" gorm:"size:100"`
 	PhotoLink string `json:"photo_link" gorm:"type:text"`
+
+	// Email is where account mails go. Until EmailVerifiedAt is set, the
+	// user has not proven they read it and cannot create items.
+	Email           string     `json:"email" gorm:"type:text;not null;default:''"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// TOTPSecret is set once the user starts enrolling in two-factor
+	// authentication, which is on from TOTPEnabledAt. TOTPLastStep is the
+	// last time step a code was accepted for, so codes cannot be replayed.
+	TOTPSecret    string     `json:"-" gorm:"column:totp_secret;size:64"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at" gorm:"column:totp_enabled_at"`
+	TOTPLastStep  int64      `json:"-" gorm:"column:totp_last_step;not null;default:0"`
+
+	// DeactivatedAt is set while an admin has deactivated the account.
+	// PasswordResetRequired blocks password logins until the user resets
+	// their password.
+	DeactivatedAt         *time.Time `json:"deactivated_at"`
+	PasswordResetRequired bool       `json:"password_reset_required" gorm:"not null;default:false"`
+
+	Roles []Role `json:"roles,omitempty" gorm:"many2many:user_roles"`
 }
 
+// Request to register with an invitation, which sets the role and position
 type RequestRegister struct {
+	InvitationToken string `json:"invitation_token" binding:"required"`
 	Username  string `json:"username" binding:"required"`
 	Password  string `json:"password" binding:"required"`
-	Position  string `json:"position"`
+	Email     string `json:"email" binding:"required,email,max=255"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	PhotoLink string `json:"photo_link"`
 }
 
+// EmailVerified reports whether the user proved they read their email.
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
 func (u User) Exists() bool {
 	return u.ID != 0 && u.Username != ""
+}
+
+func (u User) Active() bool {
+	return u.DeactivatedAt == nil
 }
\ No newline at end of file
@@ -0,0 +1,175 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithm is a password hashing algorithm.
+type Algorithm string
+
+const (
+	Argon2id Algorithm = "argon2id"
+	Bcrypt   Algorithm = "bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrUnknownHash = errors.New("password hash has an unknown format")
+
+// Argon2Params are the argon2id cost parameters.
+type Argon2Params struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Config selects how new passwords are hashed and which passwords are
+// accepted. Stored hashes carry their own algorithm and parameters, so
+// changing the configuration does not break existing passwords.
+type Config struct {
+	Algorithm  Algorithm
+	BcryptCost int
+	Argon2     Argon2Params
+	Policy     Policy
+}
+
+// ConfigFromEnv reads the configuration from PASSWORD_HASH_ALGORITHM
+// ("argon2id" or "bcrypt"), PASSWORD_BCRYPT_COST, PASSWORD_ARGON2_MEMORY
+// (KiB), PASSWORD_ARGON2_ITERATIONS, PASSWORD_ARGON2_PARALLELISM,
+// PASSWORD_MIN_LENGTH and PASSWORD_MIN_CLASSES. The argon2id defaults are
+// OWASP's recommendation.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Algorithm:  Argon2id,
+		BcryptCost: 12,
+		Argon2: Argon2Params{
+			Memory:      19 * 1024,
+			Iterations:  2,
+			Parallelism: 1,
+		},
+		Policy: Policy{
+			MinLength:  10,
+			MinClasses: 2,
+		},
+	}
+
+	switch algorithm := Algorithm(os.Getenv("PASSWORD_HASH_ALGORITHM")); algorithm {
+	case "":
+	case Argon2id, Bcrypt:
+		config.Algorithm = algorithm
+	default:
+		return config, fmt.Errorf("PASSWORD_HASH_ALGORITHM: unknown algorithm %q", algorithm)
+	}
+
+	numbers := []struct {
+		name     string
+		min, max uint64
+		set      func(uint64)
+	}{
+		{"PASSWORD_BCRYPT_COST", uint64(bcrypt.MinCost), uint64(bcrypt.MaxCost), func(v uint64) { config.BcryptCost = int(v) }},
+		{"PASSWORD_ARGON2_MEMORY", 8, 1 << 22, func(v uint64) { config.Argon2.Memory = uint32(v) }},
+		{"PASSWORD_ARGON2_ITERATIONS", 1, 100, func(v uint64) { config.Argon2.Iterations = uint32(v) }},
+		{"PASSWORD_ARGON2_PARALLELISM", 1, 255, func(v uint64) { config.Argon2.Parallelism = uint8(v) }},
+		{"PASSWORD_MIN_LENGTH", 1, maxLength, func(v uint64) { config.Policy.MinLength = int(v) }},
+		{"PASSWORD_MIN_CLASSES", 1, 4, func(v uint64) { config.Policy.MinClasses = int(v) }},
+	}
+	for _, number := range numbers {
+		value := os.Getenv(number.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || parsed < number.min || parsed > number.max {
+			return config, fmt.Errorf("%s: must be between %d and %d", number.name, number.min, number.max)
+		}
+		number.set(parsed)
+	}
+
+	return config, nil
+}
+
+// Hash hashes password with the configured algorithm. Argon2id hashes use
+// the PHC string format, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>;
+// bcrypt hashes use their usual $2a$<cost>$ format.
+func (config Config) Hash(password string) (string, error) {
+	if config.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), config.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	params := config.Argon2
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches hash, and whether hash should be
+// replaced because it was made with another algorithm or other parameters
+// than the configured ones.
+func (config Config) Verify(password string, hash string) (ok bool, rehash bool, err error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return config.verifyArgon2id(password, hash)
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, ErrUnknownHash
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false, nil
+	}
+	return true, config.Algorithm != Bcrypt || cost != config.BcryptCost, nil
+}
+
+func (config Config) verifyArgon2id(password string, hash string) (bool, bool, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrUnknownHash
+	}
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return false, false, ErrUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrUnknownHash
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return false, false, nil
+	}
+	rehash := config.Algorithm != Argon2id || params != config.Argon2 ||
+		len(salt) != argon2SaltLength || len(key) != argon2KeyLength
+	return true, rehash, nil
+}
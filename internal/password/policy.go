@@ -0,0 +1,103 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Passwords are bounded so hashing them stays cheap; bcrypt refuses
+// passwords longer than 72 bytes.
+const (
+	maxLength       = 128
+	maxBcryptLength = 72
+)
+
+// commonPasswords are rejected whatever the policy; they are the first
+// guesses of any attacker.
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"123456": true, "12345678": true, "123456789": true, "1234567890": true,
+	"qwerty": true, "qwerty123": true, "qwertyuiop": true, "1q2w3e4r": true,
+	"abc123": true, "111111": true, "iloveyou": true, "admin": true,
+	"admin123": true, "welcome": true, "welcome1": true, "letmein": true,
+	"monkey": true, "dragon": true, "sunshine": true, "football": true,
+	"changeme": true, "secret": true, "p@ssw0rd": true, "p@ssword1": true,
+}
+
+// Policy decides which passwords are strong enough.
+type Policy struct {
+	MinLength int
+	// MinClasses is how many of lower case letters, upper case letters,
+	// digits and other characters a password must mix.
+	MinClasses int
+}
+
+// PolicyError lists why a password was rejected.
+type PolicyError struct {
+	Problems []string
+}
+
+func (e PolicyError) Error() string {
+	return "password is too weak: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks password, chosen by username, against the policy.
+func (config Config) Validate(password string, username string) error {
+	var problems []string
+
+	length := len([]rune(password))
+	if length < config.Policy.MinLength {
+		problems = append(problems, fmt.Sprintf("use at least %d characters", config.Policy.MinLength))
+	}
+	limit := maxLength
+	if config.Algorithm == Bcrypt {
+		limit = maxBcryptLength
+	}
+	if len(password) > limit {
+		problems = append(problems, fmt.Sprintf("use at most %d bytes", limit))
+	}
+
+	if classes := characterClasses(password); classes < config.Policy.MinClasses {
+		problems = append(problems, fmt.Sprintf("mix at least %d of lower case letters, upper case letters, digits and symbols", config.Policy.MinClasses))
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		problems = append(problems, "it is one of the most common passwords")
+	}
+	if name := strings.ToLower(username); len(name) >= 3 && strings.Contains(lower, name) {
+		problems = append(problems, "do not include your username")
+	}
+	if length > 0 && strings.Count(password, string([]rune(password)[0])) == length {
+		problems = append(problems, "do not repeat a single character")
+	}
+
+	if len(problems) > 0 {
+		return PolicyError{Problems: problems}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
@@ -0,0 +1,115 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB) Controller {
+	return Controller{
+		Service: NewService(db),
+	}
+}
+
+func (controller Controller) FindRoles(ctx *gin.Context) {
+	roles, err := controller.Service.Roles()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": roles,
+	})
+}
+
+func (controller Controller) FindPermissions(ctx *gin.Context) {
+	permissions, err := controller.Service.Permissions()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": permissions,
+	})
+}
+
+func (controller Controller) FindUserRoles(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	roles, err := controller.Service.UserRoles(id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": roles,
+	})
+}
+
+func (controller Controller) SetUserRoles(ctx *gin.Context) {
+	// Bind
+	var request model.RequestSetUserRoles
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	roles, err := controller.Service.SetUserRoles(id, request.Roles, uid)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": roles,
+	})
+}
+
+// respondError writes the response for an error returned by the role service.
+func respondError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownUser):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrUnknownRole):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrSelfLockout):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package role
+
+import (
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) FindRoles() ([]model.Role, error) {
+	var results []model.Role
+	if err := repo.Database.Preload("Permissions").Order("id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) FindRolesByNames(names []string) ([]model.Role, error) {
+	var results []model.Role
+	if err := repo.Database.Preload("Permissions").Where("name IN ?", names).Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) FindPermissions() ([]model.Permission, error) {
+	var results []model.Permission
+	if err := repo.Database.Order("id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// FindUserRoles returns the roles assigned to a user.
+func (repo Repository) FindUserRoles(uid int) ([]model.Role, error) {
+	var results []model.Role
+	err := repo.Database.
+		Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", uid).
+		Order("roles.id").
+		Find(&results).Error
+	return results, err
+}
+
+// FindPermissionNames returns the names of all permissions a user holds
+// through their roles.
+func (repo Repository) FindPermissionNames(uid int) ([]string, error) {
+	var results []string
+	err := repo.Database.
+		Table("permissions").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", uid).
+		Pluck("permissions.name", &results).Error
+	return results, err
+}
+
+func (repo Repository) UserExists(uid int) (bool, error) {
+	var count int64
+	err := repo.Database.Model(&model.User{}).Where("id = ?", uid).Count(&count).Error
+	return count > 0, err
+}
+
+// ReplaceUserRoles sets the roles of a user to exactly roleIDs.
+func (repo Repository) ReplaceUserRoles(uid int, roleIDs []uint) error {
+	return repo.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", uid).Delete(&model.UserRole{}).Error; err != nil {
+			return err
+		}
+		if len(roleIDs) == 0 {
+			return nil
+		}
+		userRoles := make([]model.UserRole, len(roleIDs))
+		for i, id := range roleIDs {
+			userRoles[i] = model.UserRole{UserID: uint(uid), RoleID: id}
+		}
+		return tx.Create(&userRoles).Error
+	})
+}
+
+// AssignRole gives a user the named role.
+func (repo Repository) AssignRole(uid uint, name string) error {
+	return repo.Database.Exec(
+		"INSERT INTO user_roles (user_id, role_id) SELECT ?, id FROM roles WHERE name = ? ON CONFLICT DO NOTHING",
+		uid, name,
+	).Error
+}
@@ -0,0 +1,128 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownRole = errors.New("role does not exist")
+	ErrUnknownUser = errors.New("user does not exist")
+	ErrSelfLockout = errors.New("you cannot remove your own permission to manage roles")
+)
+
+type Service struct {
+	Repository Repository
+}
+
+func NewService(db *gorm.DB) Service {
+	return Service{
+		Repository: NewRepository(db),
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	return service
+}
+
+func (service Service) Roles() ([]model.Role, error) {
+	return service.Repository.FindRoles()
+}
+
+func (service Service) Permissions() ([]model.Permission, error) {
+	return service.Repository.FindPermissions()
+}
+
+func (service Service) UserRoles(uid int) ([]model.Role, error) {
+	exists, err := service.Repository.UserExists(uid)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUnknownUser
+	}
+	return service.Repository.FindUserRoles(uid)
+}
+
+// SetUserRoles replaces the roles of user uid with the named roles on
+// behalf of callerID. Callers cannot take away their own right to manage
+// roles, so the system always keeps someone able to hand them out.
+func (service Service) SetUserRoles(uid int, names []string, callerID int) ([]model.Role, error) {
+	exists, err := service.Repository.UserExists(uid)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUnknownUser
+	}
+
+	roles, err := service.Repository.FindRolesByNames(names)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make([]uint, len(roles))
+	canManage := false
+	for i, role := range roles {
+		roleIDs[i] = role.ID
+		for _, p := range role.Permissions {
+			canManage = canManage || p.Name == string(constant.RolesManagePermission)
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(roles, func(role model.Role) bool { return role.Name == name }) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, name)
+		}
+	}
+	if uid == callerID && !canManage {
+		return nil, ErrSelfLockout
+	}
+
+	if err := service.Repository.ReplaceUserRoles(uid, roleIDs); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SyncRoles replaces the roles of user uid with the named roles, e.g. as
+// mapped from an identity provider's groups. Unknown roles are skipped; a
+// user left without roles gets the default one.
+func (service Service) SyncRoles(uid uint, names []string) error {
+	roles, err := service.Repository.FindRolesByNames(names)
+	if err != nil {
+		return err
+	}
+	if len(roles) < len(slices.Compact(slices.Sorted(slices.Values(names)))) {
+		log.Printf("Skipping unknown roles among %v for user %d\n", names, uid)
+	}
+	if len(roles) == 0 {
+		if roles, err = service.Repository.FindRolesByNames([]string{string(constant.User)}); err != nil {
+			return err
+		}
+	}
+
+	roleIDs := make([]uint, len(roles))
+	for i, role := range roles {
+		roleIDs[i] = role.ID
+	}
+	return service.Repository.ReplaceUserRoles(int(uid), roleIDs)
+}
+
+// AssignDefault gives a newly registered user the User role.
+func (service Service) AssignDefault(uid uint) error {
+	return service.Repository.AssignRole(uid, string(constant.User))
+}
+
+// PermissionsFor returns the names of the permissions user uid holds.
+// It is the permission lookup of auth.Guard.
+func (service Service) PermissionsFor(uid int) ([]string, error) {
+	return service.Repository.FindPermissionNames(uid)
+}
@@ -0,0 +1,152 @@
+package rule
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB) Controller {
+	return Controller{
+		Service: NewService(db),
+	}
+}
+
+// respondError writes the response for an error returned by the service.
+func respondError(ctx *gin.Context, err error) {
+	var invalidErr InvalidExpressionError
+	switch {
+	case errors.As(err, &invalidErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": invalidErr.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": "Rule not found",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func (controller Controller) CreateRule(ctx *gin.Context) {
+	// Bind
+	var request model.RequestRule
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	rule, err := controller.Service.Create(request, uid)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data": rule,
+	})
+}
+
+func (controller Controller) FindAllRules(ctx *gin.Context) {
+	rules, err := controller.Service.FindAll()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": rules,
+	})
+}
+
+func (controller Controller) FindRuleByID(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	rule, err := controller.Service.FindByID(uint(id))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": rule,
+	})
+}
+
+func (controller Controller) UpdateRule(ctx *gin.Context) {
+	// Bind
+	var request model.RequestRule
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	rule, err := controller.Service.Update(uint(id), request)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": rule,
+	})
+}
+
+func (controller Controller) DeleteRule(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	if err := controller.Service.Delete(uint(id)); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Deleted",
+	})
+}
+
+func (controller Controller) DryRunRule(ctx *gin.Context) {
+	// Bind
+	var request model.RequestRuleDryRun
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	matched, err := controller.Service.DryRun(request)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"matched": matched},
+	})
+}
@@ -0,0 +1,91 @@
+package rule
+
+import (
+	"fmt"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// Rules are CEL expressions over two variables:
+//
+//	item      - title, amount, quantity, total, status, owner_id
+//	requester - id, username, position, first_name, last_name
+//
+// e.g. `item.total < 500 && requester.position == "Engineer"` or
+// `item.title.contains("laptop")`.
+var env, envErr = cel.NewEnv(
+	cel.Variable("item", cel.MapType(cel.StringType, cel.DynType)),
+	cel.Variable("requester", cel.MapType(cel.StringType, cel.DynType)),
+)
+
+// InvalidExpressionError is returned for expressions that do not compile to a boolean.
+type InvalidExpressionError struct {
+	Reason string
+}
+
+func (e InvalidExpressionError) Error() string {
+	return "invalid rule expression: " + e.Reason
+}
+
+func compile(expression string) (cel.Program, error) {
+	if envErr != nil {
+		return nil, envErr
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, InvalidExpressionError{Reason: issues.Err().Error()}
+	}
+	if out := ast.OutputType(); !out.IsExactType(types.BoolType) && !out.IsExactType(types.DynType) {
+		return nil, InvalidExpressionError{Reason: fmt.Sprintf("expression returns %s, not bool", out)}
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, InvalidExpressionError{Reason: err.Error()}
+	}
+	return program, nil
+}
+
+// Validate checks that the expression compiles to a boolean.
+func Validate(expression string) error {
+	_, err := compile(expression)
+	return err
+}
+
+// Evaluate runs the expression against an item and its requester.
+func Evaluate(expression string, item model.Item, requester model.User) (bool, error) {
+	program, err := compile(expression)
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := program.Eval(map[string]any{
+		"item": map[string]any{
+			"title":    item.Title,
+			"amount":   int64(item.Amount),
+			"quantity": int64(item.Quantity),
+			"total":    int64(item.Total()),
+			"status":   string(item.Status),
+			"owner_id": int64(item.OwnerID),
+		},
+		"requester": map[string]any{
+			"id":         int64(requester.ID),
+			"username":   requester.Username,
+			"position":   requester.Position,
+			"first_name": requester.FirstName,
+			"last_name":  requester.LastName,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("rule expression returned %v, not bool", out.Value())
+	}
+	return matched, nil
+}
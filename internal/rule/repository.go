@@ -0,0 +1,57 @@
+package rule
+
+import (
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) Create(rule *model.Rule) error {
+	return repo.Database.Create(rule).Error
+}
+
+func (repo Repository) FindAll() ([]model.Rule, error) {
+	var results []model.Rule
+	if err := repo.Database.Order("priority, id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) FindEnabled() ([]model.Rule, error) {
+	var results []model.Rule
+	if err := repo.Database.Where("enabled = ?", true).Order("priority, id").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (repo Repository) FindByID(id uint) (model.Rule, error) {
+	var result model.Rule
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+func (repo Repository) Save(rule model.Rule) error {
+	return repo.Database.Save(&rule).Error
+}
+
+func (repo Repository) Delete(id uint) error {
+	return repo.Database.Delete(&model.Rule{}, id).Error
+}
+
+func (repo Repository) FindUser(id int) (model.User, error) {
+	var result model.User
+	err := repo.Database.Where("id = ?", id).Find(&result).Error
+	return result, err
+}
@@ -0,0 +1,141 @@
+package rule
+
+import (
+	"errors"
+	"log"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	Repository Repository
+}
+
+func NewService(db *gorm.DB) Service {
+	return Service{
+		Repository: NewRepository(db),
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	return service
+}
+
+func (service Service) Create(req model.RequestRule, uid int) (model.Rule, error) {
+	if err := Validate(req.Expression); err != nil {
+		return model.Rule{}, err
+	}
+
+	rule := model.Rule{CreatedBy: uid, Enabled: true}
+	fill(&rule, req)
+	if err := service.Repository.Create(&rule); err != nil {
+		return model.Rule{}, err
+	}
+	return rule, nil
+}
+
+func (service Service) FindAll() ([]model.Rule, error) {
+	return service.Repository.FindAll()
+}
+
+func (service Service) FindByID(id uint) (model.Rule, error) {
+	return service.Repository.FindByID(id)
+}
+
+func (service Service) Update(id uint, req model.RequestRule) (model.Rule, error) {
+	if err := Validate(req.Expression); err != nil {
+		return model.Rule{}, err
+	}
+
+	rule, err := service.Repository.FindByID(id)
+	if err != nil {
+		return model.Rule{}, err
+	}
+	fill(&rule, req)
+	if err := service.Repository.Save(rule); err != nil {
+		return model.Rule{}, err
+	}
+	return rule, nil
+}
+
+func (service Service) Delete(id uint) error {
+	if _, err := service.Repository.FindByID(id); err != nil {
+		return err
+	}
+	return service.Repository.Delete(id)
+}
+
+// DryRun evaluates a saved rule, or an unsaved expression, against a sample item.
+func (service Service) DryRun(req model.RequestRuleDryRun) (bool, error) {
+	expression := req.Expression
+	if req.RuleID != 0 {
+		rule, err := service.Repository.FindByID(req.RuleID)
+		if err != nil {
+			return false, err
+		}
+		expression = rule.Expression
+	}
+
+	item := model.Item{
+		Title:    req.Item.Title,
+		Amount:   req.Item.Amount,
+		Quantity: req.Item.Quantity,
+		Status:   req.Item.Status,
+		OwnerID:  req.Item.OwnerID,
+	}
+	requester := model.User{
+		ID:        req.Requester.ID,
+		Username:  req.Requester.Username,
+		Position:  req.Requester.Position,
+		FirstName: req.Requester.FirstName,
+		LastName:  req.Requester.LastName,
+	}
+	matched, err := Evaluate(expression, item, requester)
+	var invalidErr InvalidExpressionError
+	if err != nil && !errors.As(err, &invalidErr) {
+		// Runtime errors come from the sample, report them like compile errors
+		return false, InvalidExpressionError{Reason: err.Error()}
+	}
+	return matched, err
+}
+
+// Match returns the enabled rules that match the item, by priority. A rule
+// that fails to evaluate is skipped so a bad rule cannot block requests.
+func (service Service) Match(item model.Item) ([]model.Rule, error) {
+	rules, err := service.Repository.FindEnabled()
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	requester, err := service.Repository.FindUser(item.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []model.Rule{}
+	for _, rule := range rules {
+		ok, err := Evaluate(rule.Expression, item, requester)
+		if err != nil {
+			log.Printf("Rule %d (%s) failed on item %d: %v\n", rule.ID, rule.Name, item.ID, err)
+			continue
+		}
+		if ok {
+			matched = append(matched, rule)
+		}
+	}
+	return matched, nil
+}
+
+func fill(rule *model.Rule, req model.RequestRule) {
+	rule.Name = req.Name
+	rule.Expression = req.Expression
+	rule.Action = req.Action
+	rule.RouteTo = req.RouteTo
+	rule.Priority = req.Priority
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+}
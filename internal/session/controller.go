@@ -0,0 +1,123 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB) Controller {
+	return Controller{
+		Service: NewService(db),
+	}
+}
+
+func (controller Controller) FindMySessions(ctx *gin.Context) {
+	// get caller and session from context
+	principal := auth.MustPrincipal(ctx)
+
+	sessions, err := controller.Service.FindByUser(uint(principal.UID), principal.SessionID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": sessions,
+	})
+}
+
+func (controller Controller) RevokeMySession(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	controller.revoke(ctx, uint(id), uint(uid))
+}
+
+// RevokeMyOtherSessions signs out everywhere but the caller's own session.
+func (controller Controller) RevokeMyOtherSessions(ctx *gin.Context) {
+	// get caller and session from context
+	principal := auth.MustPrincipal(ctx)
+
+	controller.revokeAll(ctx, uint(principal.UID), principal.SessionID)
+}
+
+func (controller Controller) FindUserSessions(ctx *gin.Context) {
+	// Path param
+	uid, _ := strconv.Atoi(ctx.Param("id"))
+
+	sessions, err := controller.Service.FindByUser(uint(uid), auth.MustPrincipal(ctx).SessionID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": sessions,
+	})
+}
+
+func (controller Controller) RevokeUserSession(ctx *gin.Context) {
+	// Path params
+	uid, _ := strconv.Atoi(ctx.Param("id"))
+	id, _ := strconv.Atoi(ctx.Param("session_id"))
+
+	controller.revoke(ctx, uint(id), uint(uid))
+}
+
+func (controller Controller) RevokeUserSessions(ctx *gin.Context) {
+	// Path param
+	uid, _ := strconv.Atoi(ctx.Param("id"))
+
+	controller.revokeAll(ctx, uint(uid), 0)
+}
+
+func (controller Controller) revoke(ctx *gin.Context, id uint, uid uint) {
+	if err := controller.Service.Revoke(id, uid); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"message": "Session not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "session signed out",
+	})
+}
+
+func (controller Controller) revokeAll(ctx *gin.Context, uid uint, exceptID uint) {
+	revoked, err := controller.Service.RevokeAll(uid, exceptID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "sessions signed out",
+		"count":   revoked,
+	})
+}
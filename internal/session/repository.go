@@ -0,0 +1,88 @@
+package session
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) Create(session *model.Session) error {
+	return repo.Database.Create(session).Error
+}
+
+func (repo Repository) FindByID(id uint) (model.Session, error) {
+	var result model.Session
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+func (repo Repository) FindByFamily(familyID string) (model.Session, error) {
+	var result model.Session
+	err := repo.Database.Where("family_id = ?", familyID).First(&result).Error
+	return result, err
+}
+
+// FindActiveByUser lists the sessions of a user that are neither signed out
+// nor expired, most recently used first.
+func (repo Repository) FindActiveByUser(uid uint, now time.Time) ([]model.Session, error) {
+	var results []model.Session
+	err := repo.Database.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", uid, now).
+		Order("last_seen_at DESC").
+		Find(&results).Error
+	return results, err
+}
+
+// Extend moves the expiry of a session along with its latest refresh token.
+func (repo Repository) Extend(id uint, expiresAt time.Time, at time.Time) error {
+	return repo.Database.
+		Model(&model.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"expires_at": expiresAt, "last_seen_at": at}).Error
+}
+
+func (repo Repository) Touch(id uint, at time.Time) error {
+	return repo.Database.Model(&model.Session{}).Where("id = ?", id).Update("last_seen_at", at).Error
+}
+
+// Revoke signs out the sessions matching query, together with their
+// refresh tokens. It returns the number of sessions signed out.
+func (repo Repository) Revoke(at time.Time, query string, args ...any) (int64, error) {
+	var revoked int64
+	err := repo.Database.Transaction(func(tx *gorm.DB) error {
+		var familyIDs []string
+		if err := tx.Model(&model.Session{}).
+			Where("revoked_at IS NULL").
+			Where(query, args...).
+			Pluck("family_id", &familyIDs).Error; err != nil {
+			return err
+		}
+		if len(familyIDs) == 0 {
+			return nil
+		}
+
+		result := tx.Model(&model.Session{}).
+			Where("family_id IN ? AND revoked_at IS NULL", familyIDs).
+			Update("revoked_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+		return tx.Model(&model.RefreshToken{}).
+			Where("family_id IN ? AND revoked_at IS NULL", familyIDs).
+			Update("revoked_at", at).Error
+	})
+	return revoked, err
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// touchInterval limits how often last_seen_at is written for a busy session.
+const touchInterval = time.Minute
+
+type Service struct {
+	Repository Repository
+}
+
+func NewService(db *gorm.DB) Service {
+	return Service{
+		Repository: NewRepository(db),
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	return service
+}
+
+// Start records a login from client whose refresh tokens form familyID.
+func (service Service) Start(uid uint, familyID string, client auth.Client, expiresAt time.Time) (model.Session, error) {
+	now := time.Now()
+	session := model.Session{
+		UserID:     uid,
+		FamilyID:   familyID,
+		Device:     describeDevice(client.UserAgent),
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		ExpiresAt:  expiresAt,
+		LastSeenAt: now,
+	}
+	err := service.Repository.Create(&session)
+	return session, err
+}
+
+func (service Service) FindByFamily(familyID string) (model.Session, error) {
+	return service.Repository.FindByFamily(familyID)
+}
+
+// Extend keeps a session alive as long as its newest refresh token.
+func (service Service) Extend(id uint, expiresAt time.Time) error {
+	return service.Repository.Extend(id, expiresAt, time.Now())
+}
+
+// IsActive is the session lookup of auth.Guard. It also records that the
+// session was seen, at most once per touchInterval.
+func (service Service) IsActive(id uint) (bool, error) {
+	session, err := service.Repository.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return false, nil
+	}
+	if now.Sub(session.LastSeenAt) > touchInterval {
+		if err := service.Repository.Touch(session.ID, now); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// FindByUser lists the active sessions of user uid, marking currentID.
+func (service Service) FindByUser(uid uint, currentID uint) ([]model.Session, error) {
+	sessions, err := service.Repository.FindActiveByUser(uid, time.Now())
+	if err != nil {
+		return sessions, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// Revoke signs out session id of user uid. Sessions of other users are
+// reported as not found.
+func (service Service) Revoke(id uint, uid uint) error {
+	revoked, err := service.Repository.Revoke(time.Now(), "id = ? AND user_id = ?", id, uid)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAll signs out every session of user uid except exceptID, and
+// returns how many were signed out.
+func (service Service) RevokeAll(uid uint, exceptID uint) (int64, error) {
+	return service.Repository.Revoke(time.Now(), "user_id = ? AND id <> ?", uid, exceptID)
+}
+
+// RevokeFamily signs out the session of a refresh token family.
+func (service Service) RevokeFamily(familyID string) error {
+	_, err := service.Repository.Revoke(time.Now(), "family_id = ?", familyID)
+	return err
+}
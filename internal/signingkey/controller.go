@@ -0,0 +1,68 @@
+package signingkey
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+	Keys    *auth.KeySet
+}
+
+func NewController(db *gorm.DB, algorithm string) Controller {
+	return Controller{
+		Service: NewService(db, algorithm),
+	}
+}
+
+// JWKS publishes the public keys tokens can be verified with.
+func (controller Controller) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, controller.Keys.JWKS())
+}
+
+func (controller Controller) FindKeys(ctx *gin.Context) {
+	keys, err := controller.Service.FindAll()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": keys,
+	})
+}
+
+func (controller Controller) RotateKey(ctx *gin.Context) {
+	key, err := controller.Service.Rotate()
+	if err != nil {
+		if errors.Is(err, ErrSymmetricAlgorithm) {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Sign with the new key right away on this instance
+	if err := controller.Keys.Reload(); err != nil {
+		log.Printf("Reloading signing keys failed: %v\n", err)
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data": key,
+	})
+}
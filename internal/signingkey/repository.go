@@ -0,0 +1,50 @@
+package signingkey
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) FindAll() ([]model.SigningKey, error) {
+	var results []model.SigningKey
+	if err := repo.Database.Order("created_at DESC").Find(&results).Error; err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// FindUsable returns the keys that still sign or verify tokens at the given
+// time, newest first.
+func (repo Repository) FindUsable(at time.Time) ([]model.SigningKey, error) {
+	var results []model.SigningKey
+	err := repo.Database.
+		Where("retired_at IS NULL OR expires_at > ?", at).
+		Order("created_at DESC").
+		Find(&results).Error
+	return results, err
+}
+
+func (repo Repository) Create(key *model.SigningKey) error {
+	return repo.Database.Create(key).Error
+}
+
+// RetireActive retires the keys that still sign tokens.
+func (repo Repository) RetireActive(at time.Time, expiresAt time.Time) error {
+	return repo.Database.
+		Model(&model.SigningKey{}).
+		Where("retired_at IS NULL").
+		Updates(map[string]any{"retired_at": at, "expires_at": expiresAt}).Error
+}
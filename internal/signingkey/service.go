@@ -0,0 +1,181 @@
+package signingkey
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrSymmetricAlgorithm is returned when rotating keys while tokens are
+// signed with JWT_SECRET.
+var ErrSymmetricAlgorithm = errors.New("tokens are signed with HS256, set JWT_ALGORITHM to RS256 or EdDSA to use signing keys")
+
+type Service struct {
+	Repository Repository
+	// Algorithm is the one new keys are generated for.
+	Algorithm string
+}
+
+func NewService(db *gorm.DB, algorithm string) Service {
+	return Service{
+		Repository: NewRepository(db),
+		Algorithm:  algorithm,
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	return service
+}
+
+func (service Service) FindAll() ([]model.SigningKey, error) {
+	return service.Repository.FindAll()
+}
+
+// Load returns the keys that sign or verify tokens. It is the loader of
+// the auth.KeySet.
+func (service Service) Load() ([]auth.SigningKey, error) {
+	stored, err := service.Repository.FindUsable(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]auth.SigningKey, 0, len(stored))
+	for _, s := range stored {
+		key := auth.SigningKey{
+			ID:        s.KID,
+			Algorithm: s.Algorithm,
+			Active:    s.RetiredAt == nil && s.Algorithm == service.Algorithm,
+		}
+		if key.Public, err = parsePublicKey(s.PublicKey); err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", s.KID, err)
+		}
+		if key.Active {
+			if key.Private, err = parsePrivateKey(s.PrivateKey); err != nil {
+				return nil, fmt.Errorf("signing key %s: %w", s.KID, err)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Ensure creates the first key when tokens are signed with an asymmetric
+// algorithm and no key of that algorithm is active yet.
+func (service Service) Ensure() error {
+	if service.Algorithm == auth.AlgorithmHS256 {
+		return nil
+	}
+	keys, err := service.Load()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.Active {
+			return nil
+		}
+	}
+	_, err = service.Rotate()
+	return err
+}
+
+// Rotate generates a new key that signs from now on. The previous keys are
+// retired but keep verifying until every token they signed has expired, so
+// live sessions survive the rotation.
+func (service Service) Rotate() (model.SigningKey, error) {
+	if service.Algorithm == auth.AlgorithmHS256 {
+		return model.SigningKey{}, ErrSymmetricAlgorithm
+	}
+
+	key, err := generateKey(service.Algorithm)
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		// Other instances may sign with a retired key until they reload
+		now := time.Now()
+		expiresAt := now.Add(auth.AccessTokenTTL() + auth.KeyReloadInterval)
+		if err := service.Repository.RetireActive(now, expiresAt); err != nil {
+			return err
+		}
+		return service.Repository.Create(&key)
+	})
+	return key, err
+}
+
+func generateKey(algorithm string) (model.SigningKey, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+	switch algorithm {
+	case auth.AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case auth.AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return model.SigningKey{}, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return model.SigningKey{}, err
+	}
+
+	return model.SigningKey{
+		KID:        hex.EncodeToString(kid),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}, nil
+}
+
+func parsePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	return signer, nil
+}
+
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
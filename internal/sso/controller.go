@@ -0,0 +1,144 @@
+package sso
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/token"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB, config Config, lockouts lockout.Service) Controller {
+	return Controller{
+		Service: NewService(db, config, lockouts),
+	}
+}
+
+// Login sends the browser to the identity provider.
+func (controller Controller) Login(ctx *gin.Context) {
+	authURL, state, err := controller.Service.AuthURL()
+	if err != nil {
+		log.Printf("Starting SSO login failed: %v\n", err)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"message": "identity provider is unavailable",
+		})
+		return
+	}
+
+	// The callback only accepts the state from the browser that asked for it
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(StateCookie, state, int(stateTTL.Seconds()), "/auth/sso", "", controller.secureCookie(), true)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// Callback is where the identity provider sends the browser back to.
+func (controller Controller) Callback(ctx *gin.Context) {
+	// Bind
+	var request model.RequestSSOCallback
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if request.Error != "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"message": "identity provider refused the login: " + request.Error,
+			"error":   request.ErrorDescription,
+		})
+		return
+	}
+
+	browserState, _ := ctx.Cookie(StateCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(StateCookie, "", -1, "/auth/sso", "", controller.secureCookie(), true)
+
+	result, err := controller.Service.Callback(ctx.Request.Context(), request, browserState, auth.ClientFrom(ctx))
+	if err != nil {
+		var lockedErr lockout.LockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"message": err.Error(),
+			})
+		case errors.Is(err, ErrInvalidState), errors.Is(err, ErrNoUsername):
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		case errors.Is(err, ErrInvalidToken):
+			log.Printf("SSO login failed: %v\n", err)
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"message": ErrInvalidToken.Error(),
+			})
+		case errors.Is(err, token.ErrAccountDeactivated):
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"message": err.Error(),
+			})
+		case errors.Is(err, ErrUsernameTaken):
+			ctx.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	// Browsers go back to the frontend, with the tokens kept out of server logs
+	if successURL := controller.Service.Config.SuccessURL; successURL != "" {
+		fragment := url.Values{}
+		if result.MFARequired {
+			fragment.Set("mfa_required", "true")
+			fragment.Set("mfa_token", result.MFAToken)
+		} else {
+			fragment.Set("token", result.Token)
+			fragment.Set("refresh_token", result.RefreshToken)
+			fragment.Set("expires_in", strconv.Itoa(result.ExpiresIn))
+			if result.MFAEnrollmentRequired {
+				fragment.Set("mfa_enrollment_required", "true")
+			}
+		}
+		ctx.Redirect(http.StatusFound, successURL+"#"+fragment.Encode())
+		return
+	}
+
+	if result.MFARequired {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":      "two-factor code required",
+			"mfa_required": true,
+			"mfa_token":    result.MFAToken,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":                 "login succeed",
+		"token":                   "Bearer " + result.Token,
+		"refresh_token":           result.RefreshToken,
+		"expires_in":              result.ExpiresIn,
+		"mfa_enrollment_required": result.MFAEnrollmentRequired,
+	})
+}
+
+// secureCookie keeps the state cookie to HTTPS when the callback is served
+// over HTTPS.
+func (controller Controller) secureCookie() bool {
+	return strings.HasPrefix(controller.Service.Config.RedirectURL, "https://")
+}
@@ -0,0 +1,92 @@
+package sso
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) CreateState(state *model.SSOLoginState) error {
+	return repo.Database.Create(state).Error
+}
+
+// TakeState deletes the login state with the given hash and returns it, so
+// a state can be used only once.
+func (repo Repository) TakeState(hash string) (model.SSOLoginState, error) {
+	var result model.SSOLoginState
+	err := repo.Database.
+		Clauses(clause.Returning{}).
+		Where("state_hash = ?", hash).
+		Delete(&result).Error
+	if err == nil && result.ID == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	return result, err
+}
+
+func (repo Repository) PurgeStates(before time.Time) error {
+	return repo.Database.Where("expires_at < ?", before).Delete(&model.SSOLoginState{}).Error
+}
+
+func (repo Repository) FindIdentity(issuer string, subject string) (model.UserIdentity, error) {
+	var result model.UserIdentity
+	err := repo.Database.Where("issuer = ? AND subject = ?", issuer, subject).First(&result).Error
+	return result, err
+}
+
+func (repo Repository) CreateIdentity(identity *model.UserIdentity) error {
+	return repo.Database.Create(identity).Error
+}
+
+func (repo Repository) TouchIdentity(id uint, at time.Time) error {
+	return repo.Database.Model(&model.UserIdentity{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
+
+func (repo Repository) FindUser(id uint) (model.User, error) {
+	var result model.User
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+func (repo Repository) FindUserByUsername(username string) (model.User, error) {
+	var result model.User
+	err := repo.Database.Where("username = ?", username).First(&result).Error
+	return result, err
+}
+
+// EmailTaken reports whether a user has email, ignoring case.
+func (repo Repository) EmailTaken(email string) (bool, error) {
+	var count int64
+	err := repo.Database.Model(&model.User{}).Where("lower(email) = lower(?)", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (repo Repository) CreateUser(user *model.User) error {
+	return repo.Database.Create(user).Error
+}
+
+// UpdateProfile copies the profile fields the provider owns onto the user.
+func (repo Repository) UpdateProfile(user model.User) error {
+	return repo.Database.
+		Model(&model.User{}).
+		Where("id = ?", user.ID).
+		Updates(map[string]any{
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"position":   user.Position,
+			"photo_link": user.PhotoLink,
+		}).Error
+}
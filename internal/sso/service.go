@@ -0,0 +1,451 @@
+package sso
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/role"
+	"github.com/Kiratopat-s/workflow/internal/token"
+	"github.com/Kiratopat-s/workflow/internal/twofactor"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"gorm.io/gorm"
+)
+
+// stateTTL is how long users have to log in at the provider.
+const stateTTL = 10 * time.Minute
+
+// StateCookie is the cookie tying a login to the browser that started it.
+const StateCookie = "sso_state"
+
+var (
+	ErrInvalidState  = errors.New("login expired or was already used, please start again")
+	ErrInvalidToken  = errors.New("identity provider returned an invalid ID token")
+	ErrNoUsername    = errors.New("ID token carries no username")
+	ErrUsernameTaken = errors.New("username belongs to a local account that is not linked to this identity")
+)
+
+// Config configures OpenID Connect single sign-on.
+type Config struct {
+	// IssuerURL is the provider's issuer; its endpoints are discovered from
+	// IssuerURL/.well-known/openid-configuration.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is this service's callback URL, /auth/sso/callback.
+	RedirectURL string
+	Scopes      []string
+	// UsernameClaim names the claim used as username; email and then the
+	// subject are used when it is missing.
+	UsernameClaim string
+	PositionClaim string
+	GroupsClaim   string
+	// GroupRoles maps provider groups to roles. When set, the roles of SSO
+	// users follow their groups on every login.
+	GroupRoles map[string][]string
+	// LinkByUsername lets the first SSO login take over the local account
+	// with the same username.
+	LinkByUsername bool
+	// SuccessURL, when set, is where the browser is sent with the tokens in
+	// the URL fragment after logging in; otherwise the tokens are returned
+	// as JSON.
+	SuccessURL string
+}
+
+// ConfigFromEnv reads the configuration from OIDC_ISSUER_URL,
+// OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL, OIDC_SCOPES,
+// OIDC_USERNAME_CLAIM, OIDC_POSITION_CLAIM, OIDC_GROUPS_CLAIM,
+// OIDC_GROUP_ROLES (e.g. "purchasing-leads=Admin,staff=User"),
+// OIDC_LINK_BY_USERNAME and OIDC_SUCCESS_URL. Single sign-on is off
+// without OIDC_ISSUER_URL.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		IssuerURL:     os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:        []string{oidc.ScopeOpenID, "profile", "email"},
+		UsernameClaim: "preferred_username",
+		PositionClaim: "position",
+		GroupsClaim:   "groups",
+		GroupRoles:    map[string][]string{},
+		SuccessURL:    os.Getenv("OIDC_SUCCESS_URL"),
+	}
+	if !config.Enabled() {
+		return config, nil
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return config, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER_URL")
+	}
+
+	if value := os.Getenv("OIDC_SCOPES"); value != "" {
+		config.Scopes = strings.Fields(strings.ReplaceAll(value, ",", " "))
+	}
+	for name, claim := range map[string]*string{
+		"OIDC_USERNAME_CLAIM": &config.UsernameClaim,
+		"OIDC_POSITION_CLAIM": &config.PositionClaim,
+		"OIDC_GROUPS_CLAIM":   &config.GroupsClaim,
+	} {
+		if value := os.Getenv(name); value != "" {
+			*claim = value
+		}
+	}
+	if value := os.Getenv("OIDC_GROUP_ROLES"); value != "" {
+		for _, pair := range strings.Split(value, ",") {
+			group, roleName, ok := strings.Cut(pair, "=")
+			group, roleName = strings.TrimSpace(group), strings.TrimSpace(roleName)
+			if !ok || group == "" || roleName == "" {
+				return config, fmt.Errorf("OIDC_GROUP_ROLES: expected group=Role, got %q", pair)
+			}
+			config.GroupRoles[group] = append(config.GroupRoles[group], roleName)
+		}
+	}
+	if value := os.Getenv("OIDC_LINK_BY_USERNAME"); value != "" {
+		link, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("OIDC_LINK_BY_USERNAME: %w", err)
+		}
+		config.LinkByUsername = link
+	}
+
+	return config, nil
+}
+
+func (config Config) Enabled() bool {
+	return config.IssuerURL != ""
+}
+
+// discovery caches the provider's metadata, fetched on first use so the
+// service starts while the provider is unreachable.
+type discovery struct {
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+type Service struct {
+	Repository Repository
+	Roles      role.Service
+	Tokens     token.Service
+	TwoFactor  twofactor.Service
+	Lockouts   lockout.Service
+	Config     Config
+	discovery  *discovery
+}
+
+func NewService(db *gorm.DB, config Config, lockouts lockout.Service) Service {
+	return Service{
+		Repository: NewRepository(db),
+		Roles:      role.NewService(db),
+		Tokens:     token.NewService(db),
+		TwoFactor:  twofactor.NewService(db, lockouts),
+		Lockouts:   lockouts,
+		Config:     config,
+		discovery:  &discovery{},
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	service.Roles = service.Roles.WithDB(db)
+	service.Tokens = service.Tokens.WithDB(db)
+	service.TwoFactor = service.TwoFactor.WithDB(db)
+	return service
+}
+
+func (service Service) provider() (*oidc.Provider, error) {
+	service.discovery.mu.Lock()
+	defer service.discovery.mu.Unlock()
+	if service.discovery.provider == nil {
+		provider, err := oidc.NewProvider(context.Background(), service.Config.IssuerURL)
+		if err != nil {
+			return nil, fmt.Errorf("discovering the identity provider failed: %w", err)
+		}
+		service.discovery.provider = provider
+	}
+	return service.discovery.provider, nil
+}
+
+func (service Service) oauth2Config(provider *oidc.Provider) oauth2.Config {
+	return oauth2.Config{
+		ClientID:     service.Config.ClientID,
+		ClientSecret: service.Config.ClientSecret,
+		RedirectURL:  service.Config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       service.Config.Scopes,
+	}
+}
+
+// AuthURL starts a login: it returns the provider URL to send the browser
+// to, carrying a fresh state, nonce and PKCE code challenge. The state is
+// returned as well, for the browser to present again at the callback.
+func (service Service) AuthURL() (string, string, error) {
+	provider, err := service.provider()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	if err := service.Repository.PurgeStates(now); err != nil {
+		return "", "", err
+	}
+	err = service.Repository.CreateState(&model.SSOLoginState{
+		StateHash:    auth.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(stateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	config := service.oauth2Config(provider)
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Callback finishes a login: it redeems the authorization code, verifies
+// the ID token and provisions the user from its claims. browserState is the
+// state the browser kept from AuthURL; a login started in another browser
+// is refused. Users with two-factor authentication get a challenge to
+// finish at /login/2fa, others our tokens.
+func (service Service) Callback(ctx context.Context, req model.RequestSSOCallback, browserState string, client auth.Client) (model.LoginResult, error) {
+	provider, err := service.provider()
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(browserState), []byte(req.State)) != 1 {
+		return model.LoginResult{}, ErrInvalidState
+	}
+	state, err := service.Repository.TakeState(auth.HashToken(req.State))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.LoginResult{}, ErrInvalidState
+	}
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	if !state.ExpiresAt.After(time.Now()) {
+		return model.LoginResult{}, ErrInvalidState
+	}
+
+	config := service.oauth2Config(provider)
+	oauth2Token, err := config.Exchange(ctx, req.Code, oauth2.VerifierOption(state.CodeVerifier))
+	if err != nil {
+		return model.LoginResult{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		return model.LoginResult{}, fmt.Errorf("%w: token response has no id_token", ErrInvalidToken)
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: service.Config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return model.LoginResult{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if idToken.Nonce != state.Nonce {
+		return model.LoginResult{}, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return model.LoginResult{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var user model.User
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		user, err = service.WithDB(tx).provision(idToken.Issuer, idToken.Subject, claims)
+		return err
+	})
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	return service.login(user, client)
+}
+
+// login signs in a user the provider authenticated, the way password
+// logins do once the password is right: locked accounts and addresses are
+// refused and users with two-factor authentication get a challenge.
+func (service Service) login(user model.User, client auth.Client) (model.LoginResult, error) {
+	if err := service.Lockouts.Check(user.Username, client.IP); err != nil {
+		return model.LoginResult{}, err
+	}
+	if !user.Active() {
+		return model.LoginResult{}, token.ErrAccountDeactivated
+	}
+
+	if user.TOTPEnabledAt != nil {
+		challenge, err := service.TwoFactor.Challenge(user)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+		return model.LoginResult{MFARequired: true, MFAToken: challenge}, nil
+	}
+
+	mustEnroll, err := service.TwoFactor.MustEnroll(user)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	pair, err := service.Tokens.Issue(user, client)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	return model.LoginResult{ResponseToken: pair, MFAEnrollmentRequired: mustEnroll}, nil
+}
+
+// provision finds or creates the user of an identity and updates their
+// profile, and roles when groups are mapped, from the ID token claims.
+func (service Service) provision(issuer string, subject string, claims map[string]any) (model.User, error) {
+	var user model.User
+	now := time.Now()
+
+	identity, err := service.Repository.FindIdentity(issuer, subject)
+	switch {
+	case err == nil:
+		if user, err = service.Repository.FindUser(identity.UserID); err != nil {
+			return user, err
+		}
+		if err := service.Repository.TouchIdentity(identity.ID, now); err != nil {
+			return user, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if user, err = service.linkOrCreate(claims, subject); err != nil {
+			return user, err
+		}
+		identity = model.UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject, LastLoginAt: &now}
+		if err := service.Repository.CreateIdentity(&identity); err != nil {
+			return user, err
+		}
+	default:
+		return user, err
+	}
+
+	// The provider owns the profile; claims it does not send are kept
+	if firstName, lastName := names(claims); firstName != "" || lastName != "" {
+		user.FirstName, user.LastName = firstName, lastName
+	}
+	if position := stringClaim(claims, service.Config.PositionClaim); position != "" {
+		user.Position = position
+	}
+	if picture := stringClaim(claims, "picture"); picture != "" {
+		user.PhotoLink = picture
+	}
+	if err := service.Repository.UpdateProfile(user); err != nil {
+		return user, err
+	}
+
+	if len(service.Config.GroupRoles) > 0 {
+		if err := service.syncRoles(user, claims); err != nil {
+			return user, err
+		}
+	}
+	return user, nil
+}
+
+// linkOrCreate returns the user an identity logging in for the first time
+// belongs to. A new user gets no password, so they can only log in here.
+func (service Service) linkOrCreate(claims map[string]any, subject string) (model.User, error) {
+	username := stringClaim(claims, service.Config.UsernameClaim)
+	// Unverified addresses could be anyone's, including a local user's name
+	if username == "" && claims["email_verified"] == true {
+		username = stringClaim(claims, "email")
+	}
+	if username == "" {
+		username = subject
+	}
+	if username == "" {
+		return model.User{}, ErrNoUsername
+	}
+
+	user, err := service.Repository.FindUserByUsername(username)
+	if err == nil {
+		if !service.Config.LinkByUsername {
+			return user, ErrUsernameTaken
+		}
+		log.Printf("Linking user %d to their SSO identity\n", user.ID)
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	// The provider vouches for the address when it says so; without one the
+	// user verifies an address of their own like everyone else
+	user = model.User{Username: username}
+	if email := stringClaim(claims, "email"); email != "" && claims["email_verified"] == true {
+		taken, err := service.Repository.EmailTaken(email)
+		if err != nil {
+			return user, err
+		}
+		if !taken {
+			now := time.Now()
+			user.Email, user.EmailVerifiedAt = email, &now
+		}
+	}
+	if err := service.Repository.CreateUser(&user); err != nil {
+		return user, err
+	}
+	if len(service.Config.GroupRoles) == 0 {
+		return user, service.Roles.AssignDefault(user.ID)
+	}
+	return user, nil
+}
+
+// syncRoles gives the user the roles their groups map to, or the default
+// role when none of their groups is mapped.
+func (service Service) syncRoles(user model.User, claims map[string]any) error {
+	var names []string
+	for _, group := range stringsClaim(claims, service.Config.GroupsClaim) {
+		names = append(names, service.Config.GroupRoles[group]...)
+	}
+	return service.Roles.SyncRoles(user.ID, names)
+}
+
+func names(claims map[string]any) (string, string) {
+	firstName, lastName := stringClaim(claims, "given_name"), stringClaim(claims, "family_name")
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(stringClaim(claims, "name"), " ")
+	}
+	return firstName, lastName
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// stringsClaim reads a claim holding a list of strings, or a single string.
+func stringsClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
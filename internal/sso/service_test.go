@@ -0,0 +1,484 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testClientID = "workflow"
+
+// testProvider is an OpenID Connect provider serving discovery, its JWKS
+// and a token endpoint that checks PKCE like a real provider does.
+type testProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant is what an authorization code was issued for.
+type grant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &testProvider{key: key, codes: map[string]grant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("/jwks", provider.jwks)
+	mux.HandleFunc("/token", provider.token)
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+func (provider *testProvider) issuer() string {
+	return provider.server.URL
+}
+
+func (provider *testProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                provider.issuer(),
+		"authorization_endpoint":                provider.issuer() + "/authorize",
+		"token_endpoint":                        provider.issuer() + "/token",
+		"jwks_uri":                              provider.issuer() + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (provider *testProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	public := provider.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func (provider *testProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	provider.mu.Lock()
+	grant, ok := provider.codes[r.PostForm.Get("code")]
+	delete(provider.codes, r.PostForm.Get("code"))
+	provider.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss": provider.issuer(),
+		"aud": testClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(provider.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+// authorize plays the user logging in at the provider: it returns a code
+// for the login started at authURL whose ID token carries claims.
+func (provider *testProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+	withNonce := jwt.MapClaims{"nonce": query.Get("nonce")}
+	for name, value := range claims {
+		withNonce[name] = value
+	}
+
+	code, err := auth.NewOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.mu.Lock()
+	provider.codes[code] = grant{challenge: query.Get("code_challenge"), claims: withNonce}
+	provider.mu.Unlock()
+	return code
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func newTestService(t *testing.T, provider *testProvider, config Config) Service {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection would get its own in-memory database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	err = db.AutoMigrate(
+		&model.User{}, &model.Role{}, &model.Permission{}, &model.UserRole{},
+		&model.UserIdentity{}, &model.SSOLoginState{},
+		&model.Session{}, &model.RefreshToken{},
+		&model.LoginChallenge{}, &model.Setting{}, &model.Lockout{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.Role{Name: string(constant.User)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	config.IssuerURL = provider.issuer()
+	config.ClientID = testClientID
+	config.RedirectURL = "https://workflow.example/auth/sso/callback"
+	config.UsernameClaim = "preferred_username"
+	config.PositionClaim = "position"
+	config.GroupsClaim = "groups"
+	lockouts := lockout.NewService(db, lockout.Config{
+		AccountThreshold: 5,
+		IPThreshold:      20,
+		BaseDuration:     time.Minute,
+		MaxDuration:      time.Hour,
+		Window:           time.Hour,
+	})
+	return NewService(db, config, lockouts)
+}
+
+// login runs a whole login: it starts it, lets the provider authorize it
+// with claims and calls back with the state the browser kept.
+func login(t *testing.T, service Service, provider *testProvider, claims jwt.MapClaims) (model.LoginResult, error) {
+	t.Helper()
+	authURL, state, err := service.AuthURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := provider.authorize(t, authURL, claims)
+	req := model.RequestSSOCallback{Code: code, State: state}
+	return service.Callback(context.Background(), req, state, auth.Client{IP: "192.0.2.1"})
+}
+
+func findUser(t *testing.T, service Service, username string) model.User {
+	t.Helper()
+	user, err := service.Repository.FindUserByUsername(username)
+	if err != nil {
+		t.Fatalf("finding %q: %v", username, err)
+	}
+	return user
+}
+
+func TestCallback(t *testing.T) {
+	provider := newTestProvider(t)
+	service := newTestService(t, provider, Config{})
+
+	result, err := login(t, service, provider, jwt.MapClaims{
+		"sub":                "alice-id",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"given_name":         "Alice",
+		"family_name":        "Smith",
+		"position":           "Manager",
+	})
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.Token == "" || result.RefreshToken == "" || result.MFARequired {
+		t.Fatalf("Callback() = %+v, want a token pair", result)
+	}
+
+	user := findUser(t, service, "alice")
+	if user.Email != "alice@example.com" || !user.EmailVerified() {
+		t.Errorf("email = %q verified %v, want the verified claim", user.Email, user.EmailVerified())
+	}
+	if user.FirstName != "Alice" || user.LastName != "Smith" || user.Position != "Manager" {
+		t.Errorf("profile = %q %q %q, want it from the claims", user.FirstName, user.LastName, user.Position)
+	}
+	var roles int64
+	service.Repository.Database.Model(&model.UserRole{}).Where("user_id = ?", user.ID).Count(&roles)
+	if roles != 1 {
+		t.Errorf("new user has %d roles, want the default role", roles)
+	}
+
+	// The second login finds the identity
+	if _, err := login(t, service, provider, jwt.MapClaims{"sub": "alice-id", "preferred_username": "renamed"}); err != nil {
+		t.Fatalf("second Callback() error = %v", err)
+	}
+	var users int64
+	service.Repository.Database.Model(&model.User{}).Count(&users)
+	if users != 1 {
+		t.Errorf("%d users after logging in twice, want 1", users)
+	}
+}
+
+func TestCallbackState(t *testing.T) {
+	provider := newTestProvider(t)
+	service := newTestService(t, provider, Config{})
+	claims := jwt.MapClaims{"sub": "alice-id", "preferred_username": "alice"}
+
+	tests := []struct {
+		name string
+		// callback returns the state the provider redirects back with and
+		// the one the browser kept
+		callback func(state string) (string, string)
+	}{
+		{"unknown state", func(state string) (string, string) { return "unknown", "unknown" }},
+		{"no state cookie", func(state string) (string, string) { return state, "" }},
+		{"state cookie of another login", func(state string) (string, string) {
+			_, other, err := service.AuthURL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return state, other
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authURL, state, err := service.AuthURL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := provider.authorize(t, authURL, claims)
+			redirectState, browserState := tt.callback(state)
+
+			req := model.RequestSSOCallback{Code: code, State: redirectState}
+			_, err = service.Callback(context.Background(), req, browserState, auth.Client{})
+			if !errors.Is(err, ErrInvalidState) {
+				t.Fatalf("Callback() error = %v, want %v", err, ErrInvalidState)
+			}
+		})
+	}
+
+	t.Run("state is used once", func(t *testing.T) {
+		authURL, state, err := service.AuthURL()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := model.RequestSSOCallback{Code: provider.authorize(t, authURL, claims), State: state}
+		if _, err := service.Callback(context.Background(), req, state, auth.Client{}); err != nil {
+			t.Fatalf("first Callback() error = %v", err)
+		}
+		req.Code = provider.authorize(t, authURL, claims)
+		if _, err := service.Callback(context.Background(), req, state, auth.Client{}); !errors.Is(err, ErrInvalidState) {
+			t.Fatalf("second Callback() error = %v, want %v", err, ErrInvalidState)
+		}
+	})
+}
+
+func TestCallbackNonce(t *testing.T) {
+	provider := newTestProvider(t)
+	service := newTestService(t, provider, Config{})
+
+	_, err := login(t, service, provider, jwt.MapClaims{"sub": "alice-id", "nonce": "replayed"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Callback() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestCallbackPKCE(t *testing.T) {
+	provider := newTestProvider(t)
+	service := newTestService(t, provider, Config{})
+
+	authURL, state, err := service.AuthURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := provider.authorize(t, authURL, jwt.MapClaims{"sub": "alice-id"})
+	// Someone who intercepted the code has no verifier for it
+	err = service.Repository.Database.Model(&model.SSOLoginState{}).
+		Where("state_hash = ?", auth.HashToken(state)).
+		Update("code_verifier", "intercepted").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := model.RequestSSOCallback{Code: code, State: state}
+	_, err = service.Callback(context.Background(), req, state, auth.Client{})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Callback() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestCallbackLinking(t *testing.T) {
+	tests := []struct {
+		name           string
+		linkByUsername bool
+		claims         jwt.MapClaims
+		wantErr        error
+		wantUsername   string
+		wantLinked     bool
+		wantEmail      string
+		wantVerified   bool
+	}{
+		{
+			name:    "local username is not taken over",
+			claims:  jwt.MapClaims{"sub": "bob-id", "preferred_username": "bob"},
+			wantErr: ErrUsernameTaken,
+		},
+		{
+			name:           "local user is linked",
+			linkByUsername: true,
+			claims:         jwt.MapClaims{"sub": "bob-id", "preferred_username": "bob"},
+			wantUsername:   "bob",
+			wantLinked:     true,
+		},
+		{
+			name:         "new user is created",
+			claims:       jwt.MapClaims{"sub": "carol-id", "preferred_username": "carol"},
+			wantUsername: "carol",
+		},
+		{
+			name:         "verified email is the username fallback",
+			claims:       jwt.MapClaims{"sub": "carol-id", "email": "carol@example.com", "email_verified": true},
+			wantUsername: "carol@example.com",
+			wantEmail:    "carol@example.com",
+			wantVerified: true,
+		},
+		{
+			name:           "unverified email is not the username fallback",
+			linkByUsername: true,
+			claims:         jwt.MapClaims{"sub": "carol-id", "email": "bob@example.com", "email_verified": false},
+			wantUsername:   "carol-id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(t)
+			service := newTestService(t, provider, Config{LinkByUsername: tt.linkByUsername})
+			local := model.User{Username: "bob", Password: "hash"}
+			if err := service.Repository.CreateUser(&local); err != nil {
+				t.Fatal(err)
+			}
+			if err := service.Repository.CreateUser(&model.User{Username: "bob@example.com", Password: "hash"}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := login(t, service, provider, tt.claims)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Callback() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Callback() error = %v", err)
+			}
+
+			user := findUser(t, service, tt.wantUsername)
+			if linked := user.ID == local.ID; linked != tt.wantLinked {
+				t.Errorf("linked to the local user = %v, want %v", linked, tt.wantLinked)
+			}
+			if user.Email != tt.wantEmail || user.EmailVerified() != tt.wantVerified {
+				t.Errorf("email = %q verified %v, want %q verified %v", user.Email, user.EmailVerified(), tt.wantEmail, tt.wantVerified)
+			}
+			identity, err := service.Repository.FindIdentity(provider.issuer(), tt.claims["sub"].(string))
+			if err != nil || identity.UserID != user.ID {
+				t.Errorf("identity = %+v (%v), want it to belong to user %d", identity, err, user.ID)
+			}
+		})
+	}
+}
+
+func TestCallbackTwoFactor(t *testing.T) {
+	provider := newTestProvider(t)
+	service := newTestService(t, provider, Config{LinkByUsername: true})
+	now := time.Now()
+	user := model.User{Username: "alice", Password: "hash", TOTPSecret: "secret", TOTPEnabledAt: &now}
+	if err := service.Repository.CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := login(t, service, provider, jwt.MapClaims{"sub": "alice-id", "preferred_username": "alice"})
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if !result.MFARequired || result.MFAToken == "" || result.Token != "" {
+		t.Fatalf("Callback() = %+v, want a two-factor challenge", result)
+	}
+}
+
+func TestCallbackLockout(t *testing.T) {
+	provider := newTestProvider(t)
+	service := newTestService(t, provider, Config{LinkByUsername: true})
+	if err := service.Repository.CreateUser(&model.User{Username: "alice", Password: "hash"}); err != nil {
+		t.Fatal(err)
+	}
+	lockedUntil := time.Now().Add(time.Hour)
+	err := service.Repository.Database.Create(&model.Lockout{
+		Kind:          constant.AccountLockout,
+		Subject:       "alice",
+		Failures:      5,
+		LockedUntil:   &lockedUntil,
+		LastFailureAt: time.Now(),
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = login(t, service, provider, jwt.MapClaims{"sub": "alice-id", "preferred_username": "alice"})
+	var lockedErr lockout.LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Callback() error = %v, want a lockout", err)
+	}
+}
@@ -0,0 +1,81 @@
+package token
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) CreateRefreshToken(token *model.RefreshToken) error {
+	return repo.Database.Create(token).Error
+}
+
+// FindRefreshTokenByHash returns the refresh token with the given hash,
+// locking it until the end of the transaction.
+func (repo Repository) FindRefreshTokenByHash(hash string) (model.RefreshToken, error) {
+	var result model.RefreshToken
+	err := repo.Database.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&result).Error
+	return result, err
+}
+
+// RevokeRefreshToken revokes a refresh token, recording the token that replaced it.
+func (repo Repository) RevokeRefreshToken(id uint, at time.Time, replacedByID *uint) error {
+	return repo.Database.
+		Model(&model.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]any{"revoked_at": at, "replaced_by_id": replacedByID}).Error
+}
+
+// RevokeFamily revokes every refresh token rotated from the same login.
+func (repo Repository) RevokeFamily(familyID string, at time.Time) error {
+	return repo.Database.
+		Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user.
+func (repo Repository) RevokeUserRefreshTokens(uid int, at time.Time) error {
+	return repo.Database.
+		Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", uid).
+		Update("revoked_at", at).Error
+}
+
+func (repo Repository) CreateRevokedToken(token model.RevokedToken) error {
+	return repo.Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error
+}
+
+// IsRevoked reports whether the access token with the given jti was revoked.
+func (repo Repository) IsRevoked(jti string) (bool, error) {
+	var count int64
+	err := repo.Database.Model(&model.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// PurgeRevokedTokens forgets revoked access tokens that expired before the given time.
+func (repo Repository) PurgeRevokedTokens(before time.Time) error {
+	return repo.Database.Where("expires_at < ?", before).Delete(&model.RevokedToken{}).Error
+}
+
+func (repo Repository) FindUser(id uint) (model.User, error) {
+	var result model.User
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
@@ -0,0 +1,255 @@
+package token
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/session"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, please log in again")
+	ErrAccountDeactivated  = errors.New("account is deactivated")
+)
+
+type Service struct {
+	Repository Repository
+	Sessions   session.Service
+}
+
+func NewService(db *gorm.DB) Service {
+	return Service{
+		Repository: NewRepository(db),
+		Sessions:   session.NewService(db),
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	service.Sessions = service.Sessions.WithDB(db)
+	return service
+}
+
+// RefreshTokenTTL is how long refresh tokens live, read from
+// REFRESH_TOKEN_TTL (a Go duration, 30 days by default).
+func RefreshTokenTTL() time.Duration {
+	if value := os.Getenv("REFRESH_TOKEN_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("Invalid REFRESH_TOKEN_TTL %q, using the default\n", value)
+	}
+	return 30 * 24 * time.Hour
+}
+
+// Issue creates an access token and a refresh token starting a new family
+// for a user who just logged in from client, recording the login as a session.
+func (service Service) Issue(user model.User, client auth.Client) (model.ResponseToken, error) {
+	if !user.Active() {
+		return model.ResponseToken{}, ErrAccountDeactivated
+	}
+	familyID, err := auth.NewOpaqueToken()
+	if err != nil {
+		return model.ResponseToken{}, err
+	}
+
+	var pair model.ResponseToken
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		session, err := service.Sessions.Start(user.ID, familyID, client, time.Now().Add(RefreshTokenTTL()))
+		if err != nil {
+			return err
+		}
+		pair, _, err = service.issue(user, familyID, session.ID)
+		return err
+	})
+	return pair, err
+}
+
+// issue creates a token pair for session sessionID whose refresh token
+// belongs to familyID.
+func (service Service) issue(user model.User, familyID string, sessionID uint) (model.ResponseToken, model.RefreshToken, error) {
+	access, err := auth.CreateToken(user.ID, user.Username, user.FirstName, user.LastName, user.Position, user.PhotoLink, sessionID)
+	if err != nil {
+		return model.ResponseToken{}, model.RefreshToken{}, err
+	}
+
+	refresh, err := auth.NewOpaqueToken()
+	if err != nil {
+		return model.ResponseToken{}, model.RefreshToken{}, err
+	}
+	stored := model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(refresh),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+	}
+	if err := service.Repository.CreateRefreshToken(&stored); err != nil {
+		return model.ResponseToken{}, model.RefreshToken{}, err
+	}
+
+	pair := model.ResponseToken{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(auth.AccessTokenTTL().Seconds()),
+	}
+	return pair, stored, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The refresh token
+// is rotated: it cannot be used again, and presenting an already rotated
+// token revokes its whole family, since either the legitimate user or a
+// thief is holding a stolen copy.
+func (service Service) Refresh(refreshToken string) (model.ResponseToken, error) {
+	var (
+		pair   model.ResponseToken
+		reused bool
+	)
+	err := service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		now := time.Now()
+
+		current, err := service.Repository.FindRefreshTokenByHash(auth.HashToken(refreshToken))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if current.RevokedAt != nil {
+			reused = true
+			if err := service.Repository.RevokeFamily(current.FamilyID, now); err != nil {
+				return err
+			}
+			return service.Sessions.RevokeFamily(current.FamilyID)
+		}
+		if !current.ExpiresAt.After(now) {
+			return ErrInvalidRefreshToken
+		}
+
+		session, err := service.Sessions.FindByFamily(current.FamilyID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if session.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		user, err := service.Repository.FindUser(current.UserID)
+		if err != nil {
+			return err
+		}
+		if !user.Active() {
+			return ErrAccountDeactivated
+		}
+		issued, stored, err := service.issue(user, current.FamilyID, session.ID)
+		if err != nil {
+			return err
+		}
+		pair = issued
+		if err := service.Sessions.Extend(session.ID, stored.ExpiresAt); err != nil {
+			return err
+		}
+		return service.Repository.RevokeRefreshToken(current.ID, now, &stored.ID)
+	})
+	if err != nil {
+		return model.ResponseToken{}, err
+	}
+	if reused {
+		log.Println("Rotated refresh token was presented again, its family is revoked")
+		return model.ResponseToken{}, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// Logout revokes the caller's access token and ends its session. A refresh
+// token given as well has its family revoked, for tokens without a session.
+func (service Service) Logout(uid int, jti string, expiresAt time.Time, sessionID uint, refreshToken string) error {
+	return service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		if err := service.revoke(jti, &uid, expiresAt); err != nil {
+			return err
+		}
+		if sessionID != 0 {
+			err := service.Sessions.Revoke(sessionID, uint(uid))
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		if refreshToken == "" {
+			return nil
+		}
+
+		current, err := service.Repository.FindRefreshTokenByHash(auth.HashToken(refreshToken))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if current.UserID != uint(uid) {
+			return nil
+		}
+		if err := service.Repository.RevokeFamily(current.FamilyID, time.Now()); err != nil {
+			return err
+		}
+		return service.Sessions.RevokeFamily(current.FamilyID)
+	})
+}
+
+// Revoke kills an access token before it expires. When uid is given, all
+// refresh tokens of that user are revoked as well, so the token cannot be
+// replaced by a refreshed one.
+func (service Service) Revoke(jti string, uid *int) error {
+	return service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		// The jti's expiry is unknown, but no access token outlives the TTL
+		if err := service.revoke(jti, uid, time.Now().Add(auth.AccessTokenTTL())); err != nil {
+			return err
+		}
+		if uid == nil {
+			return nil
+		}
+		return service.RevokeRefreshTokens(*uid)
+	})
+}
+
+// RevokeRefreshTokens revokes every refresh token of a user and ends their
+// sessions, e.g. after their password changed.
+func (service Service) RevokeRefreshTokens(uid int) error {
+	if err := service.Repository.RevokeUserRefreshTokens(uid, time.Now()); err != nil {
+		return err
+	}
+	_, err := service.Sessions.RevokeAll(uint(uid), 0)
+	return err
+}
+
+func (service Service) revoke(jti string, uid *int, expiresAt time.Time) error {
+	now := time.Now()
+	if err := service.Repository.PurgeRevokedTokens(now); err != nil {
+		return err
+	}
+	return service.Repository.CreateRevokedToken(model.RevokedToken{
+		JTI:       jti,
+		UserID:    uid,
+		ExpiresAt: expiresAt,
+		RevokedAt: now,
+	})
+}
+
+// IsRevoked is the revocation lookup of auth.Guard.
+func (service Service) IsRevoked(jti string) (bool, error) {
+	return service.Repository.IsRevoked(jti)
+}
@@ -0,0 +1,236 @@
+package twofactor
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/token"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service Service
+}
+
+func NewController(db *gorm.DB, lockouts lockout.Service) Controller {
+	return Controller{
+		Service: NewService(db, lockouts),
+	}
+}
+
+func (controller Controller) FindStatus(ctx *gin.Context) {
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	status, err := controller.Service.Status(uid)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": status,
+	})
+}
+
+func (controller Controller) Enroll(ctx *gin.Context) {
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	enrollment, err := controller.Service.Enroll(uid)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": enrollment,
+	})
+}
+
+func (controller Controller) Confirm(ctx *gin.Context) {
+	// Bind
+	var request model.RequestTwoFactorCode
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	codes, err := controller.Service.Confirm(uid, request.Code)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":        "two-factor authentication enabled, store the recovery codes safely",
+		"recovery_codes": codes,
+	})
+}
+
+func (controller Controller) Disable(ctx *gin.Context) {
+	// Bind
+	var request model.RequestTwoFactorCode
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	if err := controller.Service.Disable(uid, request, auth.ClientFrom(ctx)); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "two-factor authentication disabled",
+	})
+}
+
+func (controller Controller) RegenerateRecoveryCodes(ctx *gin.Context) {
+	// Bind
+	var request model.RequestTwoFactorCode
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	codes, err := controller.Service.RegenerateRecoveryCodes(uid, request, auth.ClientFrom(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// LoginTwoFactor completes a login started at /login with the second factor.
+func (controller Controller) LoginTwoFactor(ctx *gin.Context) {
+	// Bind
+	var request model.RequestLoginTwoFactor
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pair, err := controller.Service.CompleteLogin(request, auth.ClientFrom(ctx))
+	if err != nil {
+		var lockedErr lockout.LockedError
+		if errors.As(err, &lockedErr) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"message": lockedErr.Error(),
+			})
+			return
+		}
+		if errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrInvalidChallenge) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, token.ErrAccountDeactivated) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":       "login succeed",
+		"token":         "Bearer " + pair.Token,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+	})
+}
+
+func (controller Controller) FindSettings(ctx *gin.Context) {
+	required, err := controller.Service.RequiredForApprovers()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"required_for_approvers": required},
+	})
+}
+
+func (controller Controller) UpdateSettings(ctx *gin.Context) {
+	// Bind
+	var request model.RequestTwoFactorSettings
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	if err := controller.Service.SetRequiredForApprovers(*request.RequiredForApprovers, uid); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"required_for_approvers": *request.RequiredForApprovers},
+	})
+}
+
+// respondError writes the response for an error returned by the two-factor service.
+func respondError(ctx *gin.Context, err error) {
+	var lockedErr lockout.LockedError
+	switch {
+	case errors.As(err, &lockedErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrInvalidCode):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrAlreadyEnabled), errors.Is(err, ErrNotEnrolling), errors.Is(err, ErrNotEnabled):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package twofactor
+
+import (
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	Database *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return Repository{
+		Database: db,
+	}
+}
+
+func (repo Repository) FindUser(id uint) (model.User, error) {
+	var result model.User
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+// SaveSecret starts an enrollment with secret, which is not used for
+// logins until the enrollment is confirmed.
+func (repo Repository) SaveSecret(uid uint, secret string) error {
+	return repo.Database.
+		Model(&model.User{}).
+		Where("id = ?", uid).
+		Updates(map[string]any{"totp_secret": secret, "totp_enabled_at": nil, "totp_last_step": 0}).Error
+}
+
+func (repo Repository) Enable(uid uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.User{}).
+		Where("id = ?", uid).
+		Update("totp_enabled_at", at).Error
+}
+
+func (repo Repository) Disable(uid uint) error {
+	return repo.Database.
+		Model(&model.User{}).
+		Where("id = ?", uid).
+		Updates(map[string]any{"totp_secret": "", "totp_enabled_at": nil, "totp_last_step": 0}).Error
+}
+
+// UseStep records that a code for step was accepted. It reports false when
+// a code for this or a later step was accepted concurrently.
+func (repo Repository) UseStep(uid uint, step int64) (bool, error) {
+	result := repo.Database.
+		Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", uid, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReplaceRecoveryCodes drops the user's recovery codes and stores new ones.
+func (repo Repository) ReplaceRecoveryCodes(uid uint, hashes []string) error {
+	return repo.Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", uid).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+		codes := make([]model.RecoveryCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = model.RecoveryCode{UserID: uid, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks the unused recovery code with the given hash as
+// used. It reports false when there is no such code.
+func (repo Repository) UseRecoveryCode(uid uint, hash string, at time.Time) (bool, error) {
+	result := repo.Database.
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", uid, hash).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (repo Repository) CountUnusedRecoveryCodes(uid uint) (int, error) {
+	var count int64
+	err := repo.Database.
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", uid).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (repo Repository) CreateChallenge(challenge *model.LoginChallenge) error {
+	return repo.Database.Create(challenge).Error
+}
+
+// FindChallengeByHash returns the challenge with the given hash, locking it
+// until the end of the transaction.
+func (repo Repository) FindChallengeByHash(hash string) (model.LoginChallenge, error) {
+	var result model.LoginChallenge
+	err := repo.Database.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&result).Error
+	return result, err
+}
+
+func (repo Repository) IncrementChallengeAttempts(id uint) error {
+	return repo.Database.
+		Model(&model.LoginChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (repo Repository) DeleteChallenge(id uint) error {
+	return repo.Database.Delete(&model.LoginChallenge{}, id).Error
+}
+
+// PurgeChallenges deletes challenges that expired before the given time.
+func (repo Repository) PurgeChallenges(before time.Time) error {
+	return repo.Database.Where("expires_at < ?", before).Delete(&model.LoginChallenge{}).Error
+}
+
+func (repo Repository) FindSetting(key string) (model.Setting, error) {
+	var result model.Setting
+	err := repo.Database.Where("key = ?", key).First(&result).Error
+	return result, err
+}
+
+func (repo Repository) SaveSetting(setting model.Setting) error {
+	return repo.Database.Clauses(clause.OnConflict{UpdateAll: true}).Create(&setting).Error
+}
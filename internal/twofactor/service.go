@@ -0,0 +1,431 @@
+package twofactor
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/delegation"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/token"
+	"github.com/skip2/go-qrcode"
+
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount = 10
+	challengeTTL      = 5 * time.Minute
+	// maxChallengeAttempts is how many wrong codes a login challenge takes
+	// before it is dropped and the password has to be entered again.
+	maxChallengeAttempts = 5
+	// requiredForApproversKey is the setting making two-factor
+	// authentication mandatory for users with items:approve.
+	requiredForApproversKey = "two_factor.required_for_approvers"
+)
+
+var (
+	ErrAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrNotEnrolling     = errors.New("start the enrollment first")
+	ErrNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrInvalidCode      = errors.New("invalid two-factor code")
+	ErrInvalidChallenge = errors.New("login challenge is invalid or expired, please log in again")
+)
+
+type Service struct {
+	Repository Repository
+	Delegation delegation.Service
+	Tokens     token.Service
+	Lockouts   lockout.Service
+	// Issuer is the name authenticator apps show next to the account.
+	Issuer string
+}
+
+func NewService(db *gorm.DB, lockouts lockout.Service) Service {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Workflow"
+	}
+	return Service{
+		Repository: NewRepository(db),
+		Delegation: delegation.NewService(db),
+		Tokens:     token.NewService(db),
+		Lockouts:   lockouts,
+		Issuer:     issuer,
+	}
+}
+
+// WithDB returns a copy of the service that works on db, e.g. a transaction.
+func (service Service) WithDB(db *gorm.DB) Service {
+	service.Repository = NewRepository(db)
+	service.Delegation = service.Delegation.WithDB(db)
+	service.Tokens = service.Tokens.WithDB(db)
+	return service
+}
+
+// Enroll starts enrolling uid with a new secret. The secret is only used
+// for logins once Confirm proves the authenticator app has it.
+func (service Service) Enroll(uid int) (model.ResponseTwoFactorEnrollment, error) {
+	user, err := service.Repository.FindUser(uint(uid))
+	if err != nil {
+		return model.ResponseTwoFactorEnrollment{}, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return model.ResponseTwoFactorEnrollment{}, ErrAlreadyEnabled
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return model.ResponseTwoFactorEnrollment{}, err
+	}
+	if err := service.Repository.SaveSecret(user.ID, secret); err != nil {
+		return model.ResponseTwoFactorEnrollment{}, err
+	}
+
+	uri := otpauthURI(service.Issuer, user.Username, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return model.ResponseTwoFactorEnrollment{}, err
+	}
+	return model.ResponseTwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCodePNG:  base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// Confirm enables two-factor authentication once the user proves their
+// authenticator works, and returns their recovery codes.
+func (service Service) Confirm(uid int, code string) ([]string, error) {
+	var codes []string
+	err := service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		user, err := service.Repository.FindUser(uint(uid))
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabledAt != nil {
+			return ErrAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrNotEnrolling
+		}
+		if err := service.verifyTOTP(user, code); err != nil {
+			return err
+		}
+		if err := service.Repository.Enable(user.ID, time.Now()); err != nil {
+			return err
+		}
+		codes, err = service.newRecoveryCodes(user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable turns two-factor authentication off after checking a code.
+func (service Service) Disable(uid int, req model.RequestTwoFactorCode, client auth.Client) error {
+	return service.withCode(uid, req, client, func(service Service, user model.User) error {
+		if err := service.Repository.ReplaceRecoveryCodes(user.ID, nil); err != nil {
+			return err
+		}
+		return service.Repository.Disable(user.ID)
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a code.
+func (service Service) RegenerateRecoveryCodes(uid int, req model.RequestTwoFactorCode, client auth.Client) ([]string, error) {
+	var codes []string
+	err := service.withCode(uid, req, client, func(service Service, user model.User) error {
+		var err error
+		codes, err = service.newRecoveryCodes(user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// withCode checks a code of user uid, who has two-factor authentication
+// enabled, and runs fn in the same transaction when it is right. A stolen
+// access token must not help guessing codes, so they are locked out like
+// those of logins.
+func (service Service) withCode(uid int, req model.RequestTwoFactorCode, client auth.Client, fn func(service Service, user model.User) error) error {
+	var (
+		codeErr  error
+		username string
+	)
+	err := service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		user, err := service.enabledUser(uid)
+		if err != nil {
+			return err
+		}
+		username = user.Username
+		if err := service.Lockouts.Check(username, client.IP); err != nil {
+			return err
+		}
+		if codeErr = service.verify(user, req.Code, req.RecoveryCode); codeErr != nil {
+			return nil
+		}
+		return fn(service, user)
+	})
+	if err != nil {
+		return err
+	}
+	if codeErr != nil {
+		if err := service.Lockouts.RecordFailure(username, client.IP); err != nil {
+			return err
+		}
+		return codeErr
+	}
+	return service.Lockouts.RecordSuccess(username)
+}
+
+func (service Service) Status(uid int) (model.ResponseTwoFactorStatus, error) {
+	user, err := service.Repository.FindUser(uint(uid))
+	if err != nil {
+		return model.ResponseTwoFactorStatus{}, err
+	}
+	left, err := service.Repository.CountUnusedRecoveryCodes(user.ID)
+	if err != nil {
+		return model.ResponseTwoFactorStatus{}, err
+	}
+	required, err := service.RequiredForApprovers()
+	if err != nil {
+		return model.ResponseTwoFactorStatus{}, err
+	}
+	if required {
+		if required, err = service.isApprover(user); err != nil {
+			return model.ResponseTwoFactorStatus{}, err
+		}
+	}
+	return model.ResponseTwoFactorStatus{
+		Enabled:           user.TOTPEnabledAt != nil,
+		Required:          required,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// Challenge starts the second step of a login for a user with two-factor
+// authentication, returning the token to complete it with.
+func (service Service) Challenge(user model.User) (string, error) {
+	if err := service.Repository.PurgeChallenges(time.Now()); err != nil {
+		return "", err
+	}
+	challenge, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = service.Repository.CreateChallenge(&model.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(challenge),
+		ExpiresAt: time.Now().Add(challengeTTL),
+	})
+	return challenge, err
+}
+
+// CompleteLogin checks the second factor of a login challenge and issues
+// the tokens.
+func (service Service) CompleteLogin(req model.RequestLoginTwoFactor, client auth.Client) (model.ResponseToken, error) {
+	var (
+		pair     model.ResponseToken
+		codeErr  error
+		username string
+	)
+	err := service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		service := service.WithDB(tx)
+		challenge, err := service.Repository.FindChallengeByHash(auth.HashToken(req.MFAToken))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidChallenge
+		}
+		if err != nil {
+			return err
+		}
+		if !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= maxChallengeAttempts {
+			return ErrInvalidChallenge
+		}
+
+		user, err := service.enabledUser(int(challenge.UserID))
+		if errors.Is(err, ErrNotEnabled) {
+			return ErrInvalidChallenge
+		}
+		if err != nil {
+			return err
+		}
+		// Codes are guessed like passwords, so they are locked out alike
+		username = user.Username
+		if err := service.Lockouts.Check(username, client.IP); err != nil {
+			return err
+		}
+		// A wrong code is counted, and the transaction still commits
+		if codeErr = service.verify(user, req.Code, req.RecoveryCode); codeErr != nil {
+			return service.Repository.IncrementChallengeAttempts(challenge.ID)
+		}
+
+		if err := service.Repository.DeleteChallenge(challenge.ID); err != nil {
+			return err
+		}
+		pair, err = service.Tokens.Issue(user, client)
+		return err
+	})
+	if err != nil {
+		return model.ResponseToken{}, err
+	}
+	if codeErr != nil {
+		if err := service.Lockouts.RecordFailure(username, client.IP); err != nil {
+			return model.ResponseToken{}, err
+		}
+		return model.ResponseToken{}, codeErr
+	}
+	if err := service.Lockouts.RecordSuccess(username); err != nil {
+		return model.ResponseToken{}, err
+	}
+	return pair, nil
+}
+
+// RequiredForApprovers reports whether users with items:approve must use
+// two-factor authentication.
+func (service Service) RequiredForApprovers() (bool, error) {
+	setting, err := service.Repository.FindSetting(requiredForApproversKey)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(setting.Value)
+}
+
+func (service Service) SetRequiredForApprovers(required bool, uid int) error {
+	return service.Repository.SaveSetting(model.Setting{
+		Key:       requiredForApproversKey,
+		Value:     strconv.FormatBool(required),
+		UpdatedBy: &uid,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// MustEnroll reports whether user has approval rights that they cannot use
+// until they enable two-factor authentication.
+func (service Service) MustEnroll(user model.User) (bool, error) {
+	if user.TOTPEnabledAt != nil {
+		return false, nil
+	}
+	required, err := service.RequiredForApprovers()
+	if err != nil || !required {
+		return false, err
+	}
+	return service.isApprover(user)
+}
+
+// MayApprove reports whether user uid may decide approvals, in whatever
+// way they approve: while two-factor authentication is mandatory for
+// approvers, only users who enabled it may.
+func (service Service) MayApprove(uid int) (bool, error) {
+	required, err := service.RequiredForApprovers()
+	if err != nil || !required {
+		return true, err
+	}
+	user, err := service.Repository.FindUser(uint(uid))
+	if err != nil {
+		return false, err
+	}
+	return user.TOTPEnabledAt != nil, nil
+}
+
+// EnforcePermissions wraps a permission lookup so that users who may not
+// approve (see MayApprove) lose items:approve.
+func (service Service) EnforcePermissions(lookup auth.PermissionLookup) auth.PermissionLookup {
+	return func(uid int) ([]string, error) {
+		permissions, err := lookup(uid)
+		if err != nil || !slices.Contains(permissions, string(constant.ItemsApprovePermission)) {
+			return permissions, err
+		}
+		mayApprove, err := service.MayApprove(uid)
+		if err != nil {
+			return nil, err
+		}
+		if mayApprove {
+			return permissions, nil
+		}
+		return slices.DeleteFunc(permissions, func(p string) bool {
+			return p == string(constant.ItemsApprovePermission)
+		}), nil
+	}
+}
+
+// isApprover reports whether user approves items, by permission or by a
+// position approval levels or routing rules send items to.
+func (service Service) isApprover(user model.User) (bool, error) {
+	return service.Delegation.Repository.IsApprover(int(user.ID))
+}
+
+func (service Service) enabledUser(uid int) (model.User, error) {
+	user, err := service.Repository.FindUser(uint(uid))
+	if err != nil {
+		return user, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return user, ErrNotEnabled
+	}
+	return user, nil
+}
+
+// verify checks a TOTP code, or else a recovery code, which is used up.
+func (service Service) verify(user model.User, code string, recoveryCode string) error {
+	if code != "" {
+		return service.verifyTOTP(user, code)
+	}
+	ok, err := service.Repository.UseRecoveryCode(user.ID, auth.HashToken(normalizeRecoveryCode(recoveryCode)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (service Service) verifyTOTP(user model.User, code string) error {
+	step, ok := matchCode(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return ErrInvalidCode
+	}
+	fresh, err := service.Repository.UseStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns them in
+// the form users type them, e.g. "k3fq-9x2m".
+func (service Service) newRecoveryCodes(uid uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(secret[:4] + "-" + secret[4:8])
+		codes[i] = code
+		hashes[i] = auth.HashToken(normalizeRecoveryCode(code))
+	}
+	if err := service.Repository.ReplaceRecoveryCodes(uid, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
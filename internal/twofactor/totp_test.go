@@ -0,0 +1,87 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the secret of the RFC 4226 and RFC 6238 (SHA-1) test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, Appendix D
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range want {
+		if got := hotp(rfcSecret, int64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238, Appendix B (SHA-1), cut to the 6 digits apps use
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	secret := secretEncoding.EncodeToString(rfcSecret)
+	for _, tt := range tests {
+		if got := hotp(rfcSecret, tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+		step, ok := matchCode(secret, tt.code, time.Unix(tt.unix, 0), 0)
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("matchCode(%s) at %d = %d, %v, want step %d", tt.code, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestMatchCode(t *testing.T) {
+	secret := secretEncoding.EncodeToString(rfcSecret)
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		step     int64
+		lastStep int64
+		code     string
+		wantOK   bool
+	}{
+		{name: "current step", step: current, wantOK: true},
+		{name: "previous step", step: current - 1, wantOK: true},
+		{name: "next step", step: current + 1, wantOK: true},
+		{name: "two steps ago", step: current - 2},
+		{name: "two steps ahead", step: current + 2},
+		{name: "replayed code", step: current, lastStep: current},
+		{name: "code older than the last used one", step: current - 1, lastStep: current},
+		{name: "code newer than the last used one", step: current + 1, lastStep: current, wantOK: true},
+		{name: "spaces are ignored", step: current, code: "005 924", wantOK: true},
+		{name: "wrong length", step: current, code: "05924"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := tt.code
+			if code == "" {
+				code = hotp(rfcSecret, tt.step)
+			}
+			step, ok := matchCode(secret, code, now, tt.lastStep)
+			if ok != tt.wantOK {
+				t.Fatalf("matchCode() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.step {
+				t.Fatalf("matchCode() step = %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
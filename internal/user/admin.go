@@ -0,0 +1,134 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/apikey"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// defaultPageSize is the page size of user listings that do not ask for one.
+const defaultPageSize = 20
+
+var (
+	ErrSelfDeactivation = errors.New("you cannot deactivate your own account")
+	ErrNoPassword       = errors.New("user logs in with single sign-on and has no password to reset")
+)
+
+// FindUsers lists the users matching req a page at a time.
+func (service Service) FindUsers(req model.RequestFindUsers) ([]model.User, model.Pagination, error) {
+	page := model.Pagination{Page: req.Page, PageSize: req.PageSize}
+	if page.Page == 0 {
+		page.Page = 1
+	}
+	if page.PageSize == 0 {
+		page.PageSize = defaultPageSize
+	}
+
+	users, total, err := service.Repository.FindUsers(req, page.Page, page.PageSize)
+	page.Total = total
+	return users, page, err
+}
+
+func (service Service) FindUser(id uint) (model.User, error) {
+	return service.Repository.FindWithRoles(id)
+}
+
+// UpdateUser changes the position and roles of user id on behalf of
+// callerID. Fields left out of req are kept.
+func (service Service) UpdateUser(id uint, req model.RequestUpdateUser, callerID int) (model.User, error) {
+	err := service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		if _, err := repo.FindByID(id); err != nil {
+			return err
+		}
+		if req.Position != nil {
+			if err := repo.UpdatePosition(id, *req.Position); err != nil {
+				return err
+			}
+		}
+		if req.Roles != nil {
+			if _, err := service.Roles.WithDB(tx).SetUserRoles(int(id), *req.Roles, callerID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return service.Repository.FindWithRoles(id)
+}
+
+// Deactivate blocks user id from logging in and signs them out everywhere.
+func (service Service) Deactivate(id uint, callerID int) (model.User, error) {
+	if int(id) == callerID {
+		return model.User{}, ErrSelfDeactivation
+	}
+	err := service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		user, err := repo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if !user.Active() {
+			return nil
+		}
+		now := time.Now()
+		if err := repo.SetDeactivatedAt(id, &now); err != nil {
+			return err
+		}
+		return service.Tokens.WithDB(tx).RevokeRefreshTokens(int(id))
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return service.Repository.FindWithRoles(id)
+}
+
+// Reactivate lets a deactivated user log in again.
+func (service Service) Reactivate(id uint) (model.User, error) {
+	if _, err := service.Repository.FindByID(id); err != nil {
+		return model.User{}, err
+	}
+	if err := service.Repository.SetDeactivatedAt(id, nil); err != nil {
+		return model.User{}, err
+	}
+	return service.Repository.FindWithRoles(id)
+}
+
+// ForceReset signs user id out everywhere, revokes their API keys and
+// blocks password logins until they choose a new password with a reset link. It reports whether the link
+// could be mailed; otherwise the user has to ask for one themselves.
+func (service Service) ForceReset(id uint) (bool, error) {
+	user, err := service.Repository.FindByID(id)
+	if err != nil {
+		return false, err
+	}
+	if user.Password == "" {
+		return false, ErrNoPassword
+	}
+
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		if err := NewRepository(tx).RequirePasswordReset(id); err != nil {
+			return err
+		}
+		// Keys were handed out with the password that may have leaked
+		if err := apikey.NewRepository(tx).RevokeByUser(id, time.Now()); err != nil {
+			return err
+		}
+		return service.Tokens.WithDB(tx).RevokeRefreshTokens(int(id))
+	})
+	if err != nil {
+		return false, err
+	}
+	return service.mailResetLink(user, "An administrator asked you to choose a new password.", false)
+}
+
+// IsActive is the account lookup of auth.Guard.
+func (service Service) IsActive(uid int) (bool, error) {
+	return service.Repository.IsActive(uid)
+}
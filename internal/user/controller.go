@@ -1,10 +1,19 @@
 package user
 
 import (
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/constant"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/mail"
 	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/password"
+	"github.com/Kiratopat-s/workflow/internal/role"
+	"github.com/Kiratopat-s/workflow/internal/token"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -14,9 +23,9 @@ type Controller struct {
 	Service Service
 }
 
-func NewController(db *gorm.DB, secret string) Controller {
+func NewController(db *gorm.DB, secret string, mailer mail.Sender, lockouts lockout.Service, passwords password.Config) Controller {
 	return Controller{
-		Service: NewService(db, secret),
+		Service: NewService(db, secret, mailer, lockouts, passwords),
 	}
 }
 
@@ -32,10 +41,35 @@ func (controller Controller) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, err := controller.Service.Login(request)
+	result, err := controller.Service.Login(request, auth.ClientFrom(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
+		var lockedErr lockout.LockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"message": lockedErr.Error(),
+			})
+		case errors.Is(err, ErrInvalidCredentials):
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		case errors.Is(err, token.ErrAccountDeactivated), errors.Is(err, ErrPasswordResetRequired):
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+	if result.MFARequired {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "two-factor code required",
+			"mfa_required": true,
+			"mfa_token": result.MFAToken,
 		})
 		return
 	}
@@ -44,7 +78,10 @@ func (controller Controller) Login(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"message": "login succeed",
-		"token": "Bearer "+token,
+		"token": "Bearer "+result.Token,
+		"refresh_token": result.RefreshToken,
+		"expires_in": result.ExpiresIn,
+		"mfa_enrollment_required": result.MFAEnrollmentRequired,
 	})
 }
 
@@ -60,17 +97,481 @@ func (controller Controller) Register(ctx *gin.Context) {
 		return
 	}
 
-	fmt.Println("CTR || ",request)
-
 	err := controller.Service.Register(request)
 	if err != nil {
+		switch {
+		case errors.Is(err, ErrUsernameTaken), errors.Is(err, ErrEmailTaken):
+			ctx.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+		case errors.Is(err, ErrInvalidInvitation):
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			respondPasswordError(ctx, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "register succeed",
+	})
+}
+
+// respondPasswordError writes the response for an error returned by the password flows.
+func respondPasswordError(ctx *gin.Context, err error) {
+	var policyErr password.PolicyError
+	var lockedErr lockout.LockedError
+	switch {
+	case errors.As(err, &lockedErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"message": lockedErr.Error(),
+		})
+	case errors.As(err, &policyErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message":  policyErr.Error(),
+			"problems": policyErr.Problems,
+		})
+	case errors.Is(err, ErrWrongPassword), errors.Is(err, ErrSamePassword), errors.Is(err, ErrInvalidResetToken):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func (controller Controller) ChangePassword(ctx *gin.Context) {
+	// Bind
+	var request model.RequestChangePassword
+	if err := ctx.Bind(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"message": err.Error(),
 		})
 		return
 	}
 
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	pair, err := controller.Service.ChangePassword(uid, request, auth.ClientFrom(ctx))
+	if err != nil {
+		respondPasswordError(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "register succeed",
+		"message": "password changed",
+		"token": "Bearer "+pair.Token,
+		"refresh_token": pair.RefreshToken,
+		"expires_in": pair.ExpiresIn,
+	})
+}
+
+func (controller Controller) ForgotPassword(ctx *gin.Context) {
+	// Bind
+	var request model.RequestForgotPassword
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := controller.Service.ForgotPassword(request); err != nil {
+		respondPasswordError(ctx, err)
+		return
+	}
+
+	// Same answer whether or not the user exists
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "if the account exists, a reset link has been sent",
+	})
+}
+
+func (controller Controller) ResetPassword(ctx *gin.Context) {
+	// Bind
+	var request model.RequestResetPassword
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := controller.Service.ResetPassword(request); err != nil {
+		respondPasswordError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "password reset, please log in again",
+	})
+}
+
+// respondAdminError writes the response for an error returned by the user management service.
+func respondAdminError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, role.ErrUnknownUser):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": "User not found",
+		})
+	case errors.Is(err, role.ErrUnknownRole):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrSelfDeactivation), errors.Is(err, role.ErrSelfLockout), errors.Is(err, ErrNoPassword):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func (controller Controller) FindUsers(ctx *gin.Context) {
+	// Bind
+	var request model.RequestFindUsers
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	users, page, err := controller.Service.FindUsers(request)
+	if err != nil {
+		respondAdminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":       users,
+		"pagination": page,
+	})
+}
+
+func (controller Controller) FindUser(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	user, err := controller.Service.FindUser(uint(id))
+	if err != nil {
+		respondAdminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": user,
+	})
+}
+
+func (controller Controller) UpdateUser(ctx *gin.Context) {
+	// Bind
+	var request model.RequestUpdateUser
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	// Roles are handed out only by those allowed to manage them
+	if request.Roles != nil && !auth.HasPermission(ctx, constant.RolesManagePermission) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": "changing roles requires " + string(constant.RolesManagePermission),
+		})
+		return
+	}
+	// Positions decide who approves, so they are guarded like roles
+	if request.Position != nil && !auth.HasPermission(ctx, constant.RolesManagePermission) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": "changing positions requires " + string(constant.RolesManagePermission),
+		})
+		return
+	}
+
+	user, err := controller.Service.UpdateUser(uint(id), request, auth.MustPrincipal(ctx).UID)
+	if err != nil {
+		respondAdminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": user,
+	})
+}
+
+func (controller Controller) DeactivateUser(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	user, err := controller.Service.Deactivate(uint(id), auth.MustPrincipal(ctx).UID)
+	if err != nil {
+		respondAdminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": user,
+	})
+}
+
+func (controller Controller) ReactivateUser(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	user, err := controller.Service.Reactivate(uint(id))
+	if err != nil {
+		respondAdminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": user,
 	})
-}
\ No newline at end of file
+}
+
+func (controller Controller) ForcePasswordReset(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	mailed, err := controller.Service.ForceReset(uint(id))
+	if err != nil {
+		respondAdminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "password reset required",
+		"mailed":  mailed,
+	})
+}
+
+func (controller Controller) FindProfile(ctx *gin.Context) {
+	// get caller from context
+	principal := auth.MustPrincipal(ctx)
+
+	user, err := controller.Service.Profile(principal.UID)
+	if err != nil {
+		respondProfileError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": model.ResponseProfile{User: user, Permissions: principal.Permissions},
+	})
+}
+
+func (controller Controller) UpdateProfile(ctx *gin.Context) {
+	// Bind
+	var request model.RequestUpdateProfile
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// get caller from context
+	principal := auth.MustPrincipal(ctx)
+
+	user, err := controller.Service.UpdateProfile(principal.UID, request)
+	if err != nil {
+		respondProfileError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": model.ResponseProfile{User: user, Permissions: principal.Permissions},
+	})
+}
+
+// respondProfileError writes the response for an error returned by the profile service.
+func respondProfileError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": "User not found",
+		})
+	case errors.Is(err, ErrProtectedField):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrInvalidPhotoLink):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrEmailTaken), errors.As(err, new(ResendTooSoonError)):
+		respondEmailError(ctx, err)
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+// respondInvitationError writes the response for an error returned by the invitation service.
+func respondInvitationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": "Invitation not found",
+		})
+	case errors.Is(err, role.ErrUnknownRole):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrInvitationClosed):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func (controller Controller) CreateInvitation(ctx *gin.Context) {
+	// Bind
+	var request model.RequestCreateInvitation
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Roles other than the default are handed out only by those allowed to manage them
+	if request.Role != string(constant.User) && !auth.HasPermission(ctx, constant.RolesManagePermission) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": "inviting with the " + request.Role + " role requires " + string(constant.RolesManagePermission),
+		})
+		return
+	}
+	// Positions decide who approves, like PATCH /users/:id
+	if request.Position != "" && !auth.HasPermission(ctx, constant.RolesManagePermission) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"message": "inviting with a position requires " + string(constant.RolesManagePermission),
+		})
+		return
+	}
+
+	invitation, err := controller.Service.Invite(request, auth.MustPrincipal(ctx).UID)
+	if err != nil {
+		respondInvitationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data": invitation,
+	})
+}
+
+func (controller Controller) FindInvitations(ctx *gin.Context) {
+	// Bind
+	var request model.RequestFindInvitations
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	invitations, err := controller.Service.FindInvitations(request)
+	if err != nil {
+		respondInvitationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": invitations,
+	})
+}
+
+func (controller Controller) RevokeInvitation(ctx *gin.Context) {
+	// Path param
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	invitation, err := controller.Service.RevokeInvitation(uint(id))
+	if err != nil {
+		respondInvitationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": invitation,
+	})
+}
+
+// respondEmailError writes the response for an error returned by the email verification service.
+func respondEmailError(ctx *gin.Context, err error) {
+	var tooSoonErr ResendTooSoonError
+	switch {
+	case errors.As(err, &tooSoonErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(tooSoonErr.RetryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"message": tooSoonErr.Error(),
+		})
+	case errors.Is(err, ErrInvalidVerificationToken), errors.Is(err, ErrNoEmail):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrEmailAlreadyVerified):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"message": "User not found",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func (controller Controller) VerifyEmail(ctx *gin.Context) {
+	// Bind
+	var request model.RequestVerifyEmail
+	if err := ctx.Bind(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := controller.Service.VerifyEmail(request); err != nil {
+		respondEmailError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "email address verified",
+	})
+}
+
+func (controller Controller) ResendVerification(ctx *gin.Context) {
+	// get uid from context
+	uid := auth.MustPrincipal(ctx).UID
+
+	if err := controller.Service.ResendVerification(uid); err != nil {
+		respondEmailError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "verification link sent",
+	})
+}
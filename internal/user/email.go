@@ -0,0 +1,210 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/mail"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// maxVerificationMailsPerHour caps the verification mails a user gets,
+// however far apart they ask for them.
+const maxVerificationMailsPerHour = 5
+
+var (
+	ErrInvalidVerificationToken = errors.New("verification link is invalid or expired, please request a new one")
+	ErrEmailTaken               = errors.New("email address is already used by another account")
+	ErrEmailAlreadyVerified     = errors.New("email address is already verified")
+	ErrNoEmail                  = errors.New("account has no email address to verify, set one with PATCH /me")
+)
+
+// ResendTooSoonError is returned when a user asks for verification mails
+// faster than allowed.
+type ResendTooSoonError struct {
+	RetryAfter time.Duration
+}
+
+func (e ResendTooSoonError) Error() string {
+	return fmt.Sprintf("verification email was sent recently, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// emailVerificationTTL is how long verification links work, read from
+// EMAIL_VERIFICATION_TTL (a Go duration, 24 hours by default).
+func emailVerificationTTL() time.Duration {
+	if value := os.Getenv("EMAIL_VERIFICATION_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("Invalid EMAIL_VERIFICATION_TTL %q, using the default\n", value)
+	}
+	return 24 * time.Hour
+}
+
+// emailVerificationInterval is the least time between verification mails
+// to a user, read from EMAIL_VERIFICATION_INTERVAL (a Go duration, 1
+// minute by default).
+func emailVerificationInterval() time.Duration {
+	if value := os.Getenv("EMAIL_VERIFICATION_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err == nil && interval >= 0 {
+			return interval
+		}
+		log.Printf("Invalid EMAIL_VERIFICATION_INTERVAL %q, using the default\n", value)
+	}
+	return time.Minute
+}
+
+// emailVerificationURL is the frontend page verification links point to,
+// read from EMAIL_VERIFICATION_URL. The token is appended as the "token"
+// query parameter.
+func emailVerificationURL(token string) string {
+	base := os.Getenv("EMAIL_VERIFICATION_URL")
+	if base == "" {
+		base = "http://localhost:3000/verify-email"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+// ResendVerification mails user uid a new verification link, which makes
+// older ones stop working.
+func (service Service) ResendVerification(uid int) error {
+	user, err := service.Repository.FindByID(uint(uid))
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+	return service.mailVerificationLink(user)
+}
+
+// checkVerificationRate returns a ResendTooSoonError when user uid was sent
+// a verification mail less than the interval ago, or too many this hour.
+// The user has to be locked, so concurrent requests are counted in turn.
+func checkVerificationRate(repo Repository, uid uint) error {
+	now := time.Now()
+	recent, err := repo.FindEmailVerificationsSince(uid, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if len(recent) == 0 {
+		return nil
+	}
+	if wait := recent[0].CreatedAt.Add(emailVerificationInterval()).Sub(now); wait > 0 {
+		return ResendTooSoonError{RetryAfter: wait}
+	}
+	if len(recent) >= maxVerificationMailsPerHour {
+		oldest := recent[len(recent)-1]
+		return ResendTooSoonError{RetryAfter: oldest.CreatedAt.Add(time.Hour).Sub(now)}
+	}
+	return nil
+}
+
+// newVerificationLink creates a verification token for the email address
+// user uid has in the transaction of repo, unless the user asked for too
+// many. Only the latest token works.
+func newVerificationLink(repo Repository, uid uint) (model.User, string, error) {
+	user, err := repo.FindByIDForUpdate(uid)
+	if err != nil {
+		return user, "", err
+	}
+	if err := checkVerificationRate(repo, user.ID); err != nil {
+		return user, "", err
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return user, "", err
+	}
+	if err := repo.InvalidateEmailVerifications(user.ID, time.Now()); err != nil {
+		return user, "", err
+	}
+	err = repo.CreateEmailVerification(&model.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL()),
+	})
+	return user, token, err
+}
+
+// mailVerificationLink creates a verification token for the email address
+// of user and mails them the link.
+func (service Service) mailVerificationLink(user model.User) error {
+	var token string
+	err := service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, token, err = newVerificationLink(NewRepository(tx), user.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	service.sendVerificationLink(user, token)
+	return nil
+}
+
+// sendVerificationLink mails user the link to verify their address with
+// token. Failures are only logged: the token is stored, and the user can
+// ask for another link.
+func (service Service) sendVerificationLink(user model.User, token string) {
+	message := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Open the link below within %s to verify your email address:\n\n%s\n\n"+
+			"If you did not create an account, you can ignore this email.\n",
+			user.FirstName, emailVerificationTTL(), emailVerificationURL(token)),
+	}
+	if err := service.Mailer.Send(message); err != nil {
+		log.Printf("Sending verification mail to user %d failed: %v\n", user.ID, err)
+	}
+}
+
+// VerifyEmail marks the email address a verification token was sent to as
+// verified. The token is used up; it stops working once the address changes.
+func (service Service) VerifyEmail(req model.RequestVerifyEmail) error {
+	return service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		verification, err := repo.FindEmailVerificationByHash(auth.HashToken(req.Token))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if verification.UsedAt != nil || !verification.ExpiresAt.After(now) {
+			return ErrInvalidVerificationToken
+		}
+
+		user, err := repo.FindByID(verification.UserID)
+		if err != nil {
+			return err
+		}
+		if user.Email != verification.Email {
+			return ErrInvalidVerificationToken
+		}
+		if err := repo.SetEmailVerifiedAt(user.ID, now); err != nil {
+			return err
+		}
+		return repo.InvalidateEmailVerifications(user.ID, now)
+	})
+}
+
+// IsEmailVerified is the lookup of auth.RequireVerifiedEmail.
+func (service Service) IsEmailVerified(uid int) (bool, error) {
+	return service.Repository.IsEmailVerified(uid)
+}
@@ -0,0 +1,156 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/mail"
+	"github.com/Kiratopat-s/workflow/internal/model"
+	"github.com/Kiratopat-s/workflow/internal/role"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidInvitation = errors.New("invitation is invalid, used or expired, please ask for a new one")
+	ErrInvitationClosed  = errors.New("invitation was already accepted or revoked")
+	ErrUsernameTaken     = errors.New("username already taken")
+)
+
+// invitationTTL is how long invitations work, read from INVITATION_TTL
+// (a Go duration, 7 days by default).
+func invitationTTL() time.Duration {
+	if value := os.Getenv("INVITATION_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("Invalid INVITATION_TTL %q, using the default\n", value)
+	}
+	return 7 * 24 * time.Hour
+}
+
+// invitationURL is the frontend page invitation links point to, read from
+// INVITATION_URL. The token is appended as the "token" query parameter.
+func invitationURL(token string) string {
+	base := os.Getenv("INVITATION_URL")
+	if base == "" {
+		base = "http://localhost:3000/register"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+// Invite creates an invitation to register with the role and position of
+// req on behalf of callerID. The link is mailed when req has an email
+// address; the token is returned either way and cannot be looked up later.
+func (service Service) Invite(req model.RequestCreateInvitation, callerID int) (model.ResponseInvitation, error) {
+	roles, err := service.Roles.Repository.FindRolesByNames([]string{req.Role})
+	if err != nil {
+		return model.ResponseInvitation{}, err
+	}
+	if len(roles) == 0 {
+		return model.ResponseInvitation{}, fmt.Errorf("%w: %s", role.ErrUnknownRole, req.Role)
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return model.ResponseInvitation{}, err
+	}
+	invitation := model.Invitation{
+		TokenHash: auth.HashToken(token),
+		Email:     req.Email,
+		RoleID:    roles[0].ID,
+		Position:  req.Position,
+		CreatedBy: uint(callerID),
+		ExpiresAt: time.Now().Add(invitationTTL()),
+	}
+	if err := service.Repository.CreateInvitation(&invitation); err != nil {
+		return model.ResponseInvitation{}, err
+	}
+	invitation.Role = roles[0]
+	invitation.Role.Permissions = nil
+	invitation.Status = model.InvitationPending
+
+	response := model.ResponseInvitation{Invitation: invitation, Token: token, URL: invitationURL(token)}
+	if req.Email != "" {
+		message := mail.Message{
+			To:      req.Email,
+			Subject: "You are invited to Workflow",
+			Body: fmt.Sprintf("Hello,\n\nYou have been invited to create an account. "+
+				"Open the link below within %s to register:\n\n%s\n",
+				invitationTTL(), response.URL),
+		}
+		if err := service.Mailer.Send(message); err != nil {
+			log.Printf("Sending invitation %d failed: %v\n", invitation.ID, err)
+		} else {
+			response.Mailed = true
+		}
+	}
+	return response, nil
+}
+
+// FindInvitations lists invitations, optionally only those with a status.
+func (service Service) FindInvitations(req model.RequestFindInvitations) ([]model.Invitation, error) {
+	now := time.Now()
+	invitations, err := service.Repository.FindInvitations(req.Status, now)
+	for i := range invitations {
+		invitations[i].Status = invitations[i].StatusAt(now)
+	}
+	return invitations, err
+}
+
+// RevokeInvitation stops invitation id from being used. Accepted and
+// revoked invitations cannot be revoked; expired ones can.
+func (service Service) RevokeInvitation(id uint) (model.Invitation, error) {
+	invitation, err := service.Repository.FindInvitation(id)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return model.Invitation{}, ErrInvitationClosed
+	}
+	now := time.Now()
+	if err := service.Repository.RevokeInvitation(id, now); err != nil {
+		return model.Invitation{}, err
+	}
+	invitation.RevokedAt = &now
+	invitation.Status = invitation.StatusAt(now)
+	return invitation, nil
+}
+
+// acceptInvitation uses up the invitation with token on behalf of user,
+// who gets its role and position. It runs inside the registration's
+// transaction tx, before user is created.
+func (service Service) acceptInvitation(tx *gorm.DB, token string, user *model.User) error {
+	repo := NewRepository(tx)
+	invitation, err := repo.FindInvitationByHash(auth.HashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidInvitation
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if invitation.StatusAt(now) != model.InvitationPending {
+		return ErrInvalidInvitation
+	}
+
+	user.Position = invitation.Position
+	// The invitation was mailed to this address, which proves it is theirs
+	if invitation.Email != "" && strings.EqualFold(invitation.Email, user.Email) {
+		user.EmailVerifiedAt = &now
+	}
+	if err := repo.Register(user); err != nil {
+		return err
+	}
+	if err := service.Roles.WithDB(tx).Repository.ReplaceUserRoles(int(user.ID), []uint{invitation.RoleID}); err != nil {
+		return err
+	}
+	return repo.AcceptInvitation(invitation.ID, user.ID, now)
+}
@@ -0,0 +1,244 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/mail"
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Users asking for reset links themselves get at most one a minute and
+// maxResetMailsPerHour an hour, however far apart they ask for them.
+const (
+	resetMailInterval    = time.Minute
+	maxResetMailsPerHour = 5
+)
+
+// errResetTooSoon is returned when a user asks for reset links faster than
+// allowed. It never reaches the caller, who would learn the user exists.
+var errResetTooSoon = errors.New("password reset link was sent recently")
+
+var (
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrSamePassword      = errors.New("new password must differ from the current one")
+	ErrInvalidResetToken = errors.New("reset link is invalid or expired, please request a new one")
+)
+
+// passwordResetTTL is how long reset links work, read from
+// PASSWORD_RESET_TTL (a Go duration, 30 minutes by default).
+func passwordResetTTL() time.Duration {
+	if value := os.Getenv("PASSWORD_RESET_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("Invalid PASSWORD_RESET_TTL %q, using the default\n", value)
+	}
+	return 30 * time.Minute
+}
+
+// passwordResetURL is the frontend page reset links point to, read from
+// PASSWORD_RESET_URL. The token is appended as the "token" query parameter.
+func passwordResetURL(token string) string {
+	base := os.Getenv("PASSWORD_RESET_URL")
+	if base == "" {
+		base = "http://localhost:3000/reset-password"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+// ChangePassword changes the password of a logged in user. Other sessions
+// are signed out; the caller gets a fresh token pair. Wrong current
+// passwords count as failed logins, and while the account or client
+// address is locked ChangePassword returns a lockout.LockedError.
+func (service Service) ChangePassword(uid int, req model.RequestChangePassword, client auth.Client) (model.ResponseToken, error) {
+	user, err := service.Repository.FindByID(uint(uid))
+	if err != nil {
+		return model.ResponseToken{}, err
+	}
+	if err := service.Lockouts.Check(user.Username, client.IP); err != nil {
+		return model.ResponseToken{}, err
+	}
+	ok, err := service.checkPassword(user, req.CurrentPassword)
+	if err != nil {
+		return model.ResponseToken{}, err
+	}
+	if !ok {
+		if err := service.Lockouts.RecordFailure(user.Username, client.IP); err != nil {
+			return model.ResponseToken{}, err
+		}
+		return model.ResponseToken{}, ErrWrongPassword
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return model.ResponseToken{}, ErrSamePassword
+	}
+	if err := service.Passwords.Validate(req.NewPassword, user.Username); err != nil {
+		return model.ResponseToken{}, err
+	}
+
+	hash, err := service.Passwords.Hash(req.NewPassword)
+	if err != nil {
+		return model.ResponseToken{}, err
+	}
+
+	var pair model.ResponseToken
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		if err := NewRepository(tx).UpdatePassword(user.ID, hash); err != nil {
+			return err
+		}
+		tokens := service.Tokens.WithDB(tx)
+		if err := tokens.RevokeRefreshTokens(uid); err != nil {
+			return err
+		}
+		pair, err = tokens.Issue(user, client)
+		return err
+	})
+	return pair, err
+}
+
+// ForgotPassword mails a reset link to the user. It succeeds whether or not
+// the user exists, so it cannot be used to find out usernames.
+func (service Service) ForgotPassword(req model.RequestForgotPassword) error {
+	user, err := service.Repository.FindOneByUsername(req.Username)
+	if err != nil {
+		return err
+	}
+	if !user.Exists() {
+		return nil
+	}
+	if user.Password == "" {
+		log.Printf("Password reset requested for user %d, who logs in with single sign-on\n", user.ID)
+		return nil
+	}
+
+	_, err = service.mailResetLink(user, "Someone asked to reset the password of your account.", true)
+	if errors.Is(err, errResetTooSoon) {
+		log.Printf("Password reset link for user %d not sent, they asked too often\n", user.ID)
+		return nil
+	}
+	return err
+}
+
+// checkResetRate returns errResetTooSoon when user uid was sent a reset link
+// less than resetMailInterval ago, or too many this hour. The user has to be
+// locked, so concurrent requests are counted in turn.
+func checkResetRate(repo Repository, uid uint) error {
+	now := time.Now()
+	recent, err := repo.FindPasswordResetsSince(uid, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if len(recent) == 0 {
+		return nil
+	}
+	if recent[0].CreatedAt.Add(resetMailInterval).After(now) || len(recent) >= maxResetMailsPerHour {
+		return errResetTooSoon
+	}
+	return nil
+}
+
+// mailResetLink creates a reset token for user and mails them the link,
+// explaining why with reason. With limited, users who were sent too many
+// links get errResetTooSoon instead. It reports false when the user has no
+// email address to send it to.
+func (service Service) mailResetLink(user model.User, reason string, limited bool) (bool, error) {
+	// Users without a verified email address get it when their username is one
+	to := user.Username
+	if user.EmailVerified() {
+		to = user.Email
+	}
+	address, err := netmail.ParseAddress(to)
+	if err != nil {
+		log.Printf("Password reset link for user %d not sent, they have no email address\n", user.ID)
+		return false, nil
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return false, err
+	}
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		if limited {
+			if _, err := repo.FindByIDForUpdate(user.ID); err != nil {
+				return err
+			}
+			if err := checkResetRate(repo, user.ID); err != nil {
+				return err
+			}
+		}
+		// Only the latest link works
+		if err := repo.InvalidatePasswordResets(user.ID, time.Now()); err != nil {
+			return err
+		}
+		return repo.CreatePasswordReset(&model.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken(token),
+			ExpiresAt: time.Now().Add(passwordResetTTL()),
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+
+	message := mail.Message{
+		To:      address.Address,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n%s "+
+			"Open the link below within %s to choose a new password:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.FirstName, reason, passwordResetTTL(), passwordResetURL(token)),
+	}
+	if err := service.Mailer.Send(message); err != nil {
+		log.Printf("Sending password reset mail to user %d failed: %v\n", user.ID, err)
+		return false, nil
+	}
+	return true, nil
+}
+
+// ResetPassword sets a new password with a reset token, which is used up.
+// All sessions of the user are signed out.
+func (service Service) ResetPassword(req model.RequestResetPassword) error {
+	return service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		reset, err := repo.FindPasswordResetByHash(auth.HashToken(req.Token))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if reset.UsedAt != nil || !reset.ExpiresAt.After(time.Now()) {
+			return ErrInvalidResetToken
+		}
+
+		user, err := repo.FindByID(reset.UserID)
+		if err != nil {
+			return err
+		}
+		if err := service.Passwords.Validate(req.NewPassword, user.Username); err != nil {
+			return err
+		}
+		hash, err := service.Passwords.Hash(req.NewPassword)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.UpdatePassword(reset.UserID, hash); err != nil {
+			return err
+		}
+		if err := repo.InvalidatePasswordResets(reset.UserID, time.Now()); err != nil {
+			return err
+		}
+		return service.Tokens.WithDB(tx).RevokeRefreshTokens(int(reset.UserID))
+	})
+}
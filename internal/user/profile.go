@@ -0,0 +1,89 @@
+package user
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/Kiratopat-s/workflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProtectedField   = errors.New("position and username can only be changed by an administrator")
+	ErrInvalidPhotoLink = errors.New("photo_link must be an http or https URL")
+)
+
+// Profile returns user uid as stored, with their roles.
+func (service Service) Profile(uid int) (model.User, error) {
+	return service.Repository.FindWithRoles(uint(uid))
+}
+
+// UpdateProfile changes the names, photo and email address of user uid.
+// Fields left out of req are kept. A new email address is unverified until
+// the user opens the link mailed to it. Tokens issued afterwards, including
+// refreshed ones, carry the new profile.
+func (service Service) UpdateProfile(uid int, req model.RequestUpdateProfile) (model.User, error) {
+	if req.PhotoLink != nil && *req.PhotoLink != "" {
+		link, err := url.Parse(*req.PhotoLink)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			return model.User{}, ErrInvalidPhotoLink
+		}
+	}
+
+	user, err := service.Repository.FindByID(uint(uid))
+	if err != nil {
+		return model.User{}, err
+	}
+	// Clients may send the whole profile back, unchanged fields included
+	if (req.Position != nil && *req.Position != user.Position) || (req.Username != nil && *req.Username != user.Username) {
+		return model.User{}, ErrProtectedField
+	}
+	// A new address has to be verified again
+	emailChanged := req.Email != nil && *req.Email != user.Email
+	if emailChanged {
+		taken, err := service.Repository.EmailTaken(*req.Email, user.ID)
+		if err != nil {
+			return model.User{}, err
+		}
+		if taken {
+			return model.User{}, ErrEmailTaken
+		}
+	}
+	updates := map[string]any{}
+	if req.FirstName != nil {
+		updates["first_name"] = *req.FirstName
+	}
+	if req.LastName != nil {
+		updates["last_name"] = *req.LastName
+	}
+	if req.PhotoLink != nil {
+		updates["photo_link"] = *req.PhotoLink
+	}
+
+	// The address only changes along with a link to verify it
+	var token string
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		if len(updates) > 0 {
+			if err := repo.UpdateProfile(uint(uid), updates); err != nil {
+				return err
+			}
+		}
+		if !emailChanged {
+			return nil
+		}
+		if err := repo.UpdateEmail(user.ID, *req.Email); err != nil {
+			return err
+		}
+		user, token, err = newVerificationLink(repo, user.ID)
+		return err
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	if emailChanged {
+		service.sendVerificationLink(user, token)
+	}
+	return service.Repository.FindWithRoles(uint(uid))
+}
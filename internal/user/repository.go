@@ -1,11 +1,20 @@
 package user
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/Kiratopat-s/workflow/internal/model"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type Repository struct {
 	Database *gorm.DB
 }
@@ -32,8 +41,262 @@ func (repo Repository) FindOneByUsername(username string) (model.User, error) {
 func (repo Repository) Register(user *model.User) error {
 	db := repo.Database
 	if err := db.Create(user).Error; err != nil {
-		return err
+		return emailTakenError(err)
 	}
 
 	return nil
 }
+
+func (repo Repository) FindByID(id uint) (model.User, error) {
+	var result model.User
+	err := repo.Database.First(&result, id).Error
+	return result, err
+}
+
+// FindByIDForUpdate returns the user with the given id, locking them until
+// the end of the transaction.
+func (repo Repository) FindByIDForUpdate(id uint) (model.User, error) {
+	var result model.User
+	err := repo.Database.Clauses(clause.Locking{Strength: "UPDATE"}).First(&result, id).Error
+	return result, err
+}
+
+// UpdatePassword sets a new password, which also satisfies a required reset.
+func (repo Repository) UpdatePassword(id uint, hash string) error {
+	return repo.Database.
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"password": hash, "password_reset_required": false}).Error
+}
+
+// FindUsers returns a page of the users matching req, with their roles, and
+// the number of matching users.
+func (repo Repository) FindUsers(req model.RequestFindUsers, page int, pageSize int) ([]model.User, int64, error) {
+	var (
+		results []model.User
+		total   int64
+	)
+
+	db := repo.Database.Model(&model.User{})
+	if req.Query != "" {
+		pattern := "%" + likeEscaper.Replace(req.Query) + "%"
+		db = db.Where("username ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ? OR position ILIKE ?", pattern, pattern, pattern, pattern)
+	}
+	switch req.Status {
+	case "active":
+		db = db.Where("deactivated_at IS NULL")
+	case "deactivated":
+		db = db.Where("deactivated_at IS NOT NULL")
+	}
+	if req.Role != "" {
+		db = db.Where("id IN (?)", repo.Database.
+			Table("user_roles").
+			Select("user_roles.user_id").
+			Joins("JOIN roles ON roles.id = user_roles.role_id").
+			Where("roles.name = ?", req.Role))
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return results, total, err
+	}
+	err := db.
+		Preload("Roles").
+		Order("id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&results).Error
+	return results, total, err
+}
+
+func (repo Repository) FindWithRoles(id uint) (model.User, error) {
+	var result model.User
+	err := repo.Database.Preload("Roles").First(&result, id).Error
+	return result, err
+}
+
+// UpdateProfile sets the given profile columns of a user.
+func (repo Repository) UpdateProfile(id uint, updates map[string]any) error {
+	return repo.Database.Model(&model.User{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (repo Repository) UpdatePosition(id uint, position string) error {
+	return repo.Database.Model(&model.User{}).Where("id = ?", id).Update("position", position).Error
+}
+
+func (repo Repository) SetDeactivatedAt(id uint, at *time.Time) error {
+	return repo.Database.Model(&model.User{}).Where("id = ?", id).Update("deactivated_at", at).Error
+}
+
+func (repo Repository) RequirePasswordReset(id uint) error {
+	return repo.Database.Model(&model.User{}).Where("id = ?", id).Update("password_reset_required", true).Error
+}
+
+// IsActive reports whether the user exists and is not deactivated.
+func (repo Repository) IsActive(id int) (bool, error) {
+	var count int64
+	err := repo.Database.Model(&model.User{}).Where("id = ? AND deactivated_at IS NULL", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (repo Repository) CreatePasswordReset(reset *model.PasswordReset) error {
+	return repo.Database.Create(reset).Error
+}
+
+// InvalidatePasswordResets uses up the unused reset tokens of a user.
+func (repo Repository) InvalidatePasswordResets(uid uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.PasswordReset{}).
+		Where("user_id = ? AND used_at IS NULL", uid).
+		Update("used_at", at).Error
+}
+
+// FindPasswordResetsSince returns the reset tokens created for a user since
+// the given time, newest first.
+func (repo Repository) FindPasswordResetsSince(uid uint, since time.Time) ([]model.PasswordReset, error) {
+	var results []model.PasswordReset
+	err := repo.Database.
+		Where("user_id = ? AND created_at > ?", uid, since).
+		Order("created_at DESC").
+		Find(&results).Error
+	return results, err
+}
+
+// FindPasswordResetByHash returns the reset token with the given hash,
+// locking it until the end of the transaction.
+func (repo Repository) FindPasswordResetByHash(hash string) (model.PasswordReset, error) {
+	var result model.PasswordReset
+	err := repo.Database.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&result).Error
+	return result, err
+}
+
+func (repo Repository) CreateInvitation(invitation *model.Invitation) error {
+	return repo.Database.Create(invitation).Error
+}
+
+// FindInvitations lists invitations with their roles, newest first.
+// status narrows the list to one of the model.Invitation statuses.
+func (repo Repository) FindInvitations(status string, now time.Time) ([]model.Invitation, error) {
+	var results []model.Invitation
+	db := repo.Database.Preload("Role").Order("created_at DESC")
+	switch status {
+	case model.InvitationAccepted:
+		db = db.Where("accepted_at IS NOT NULL")
+	case model.InvitationRevoked:
+		db = db.Where("accepted_at IS NULL AND revoked_at IS NOT NULL")
+	case model.InvitationExpired:
+		db = db.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	case model.InvitationPending:
+		db = db.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	}
+	err := db.Find(&results).Error
+	return results, err
+}
+
+func (repo Repository) FindInvitation(id uint) (model.Invitation, error) {
+	var result model.Invitation
+	err := repo.Database.Preload("Role").First(&result, id).Error
+	return result, err
+}
+
+// FindInvitationByHash returns the invitation with the given token hash,
+// locking it until the end of the transaction.
+func (repo Repository) FindInvitationByHash(hash string) (model.Invitation, error) {
+	var result model.Invitation
+	err := repo.Database.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&result).Error
+	return result, err
+}
+
+func (repo Repository) AcceptInvitation(id uint, uid uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.Invitation{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"accepted_at": at, "accepted_by": uid}).Error
+}
+
+func (repo Repository) RevokeInvitation(id uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.Invitation{}).
+		Where("id = ?", id).
+		Update("revoked_at", at).Error
+}
+
+// EmailTaken reports whether a user other than exceptID has email,
+// ignoring case.
+func (repo Repository) EmailTaken(email string, exceptID uint) (bool, error) {
+	var count int64
+	err := repo.Database.Model(&model.User{}).
+		Where("lower(email) = lower(?) AND id <> ?", email, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateEmail sets a new, unverified email address.
+func (repo Repository) UpdateEmail(id uint, email string) error {
+	err := repo.Database.
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"email": email, "email_verified_at": nil}).Error
+	return emailTakenError(err)
+}
+
+// emailTakenError turns a violation of the unique index on email addresses
+// into ErrEmailTaken. EmailTaken cannot rule it out, since another request
+// may take the address right after.
+func emailTakenError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_users_email" {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func (repo Repository) SetEmailVerifiedAt(id uint, at time.Time) error {
+	return repo.Database.Model(&model.User{}).Where("id = ?", id).Update("email_verified_at", at).Error
+}
+
+// IsEmailVerified reports whether the user exists and verified their email.
+func (repo Repository) IsEmailVerified(id int) (bool, error) {
+	var count int64
+	err := repo.Database.Model(&model.User{}).Where("id = ? AND email_verified_at IS NOT NULL", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (repo Repository) CreateEmailVerification(verification *model.EmailVerification) error {
+	return repo.Database.Create(verification).Error
+}
+
+// InvalidateEmailVerifications uses up the unused verification tokens of a user.
+func (repo Repository) InvalidateEmailVerifications(uid uint, at time.Time) error {
+	return repo.Database.
+		Model(&model.EmailVerification{}).
+		Where("user_id = ? AND used_at IS NULL", uid).
+		Update("used_at", at).Error
+}
+
+// FindEmailVerificationByHash returns the verification token with the
+// given hash, locking it until the end of the transaction.
+func (repo Repository) FindEmailVerificationByHash(hash string) (model.EmailVerification, error) {
+	var result model.EmailVerification
+	err := repo.Database.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&result).Error
+	return result, err
+}
+
+// FindEmailVerificationsSince returns the verification tokens created for
+// a user since the given time, newest first.
+func (repo Repository) FindEmailVerificationsSince(uid uint, since time.Time) ([]model.EmailVerification, error) {
+	var results []model.EmailVerification
+	err := repo.Database.
+		Where("user_id = ? AND created_at > ?", uid, since).
+		Order("created_at DESC").
+		Find(&results).Error
+	return results, err
+}
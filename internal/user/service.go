@@ -2,55 +2,138 @@ package user
 
 import (
 	"errors"
-	"fmt"
+	"log"
 
 	"github.com/Kiratopat-s/workflow/internal/auth"
+	"github.com/Kiratopat-s/workflow/internal/lockout"
+	"github.com/Kiratopat-s/workflow/internal/mail"
 	"github.com/Kiratopat-s/workflow/internal/model"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/Kiratopat-s/workflow/internal/password"
+	"github.com/Kiratopat-s/workflow/internal/role"
+	"github.com/Kiratopat-s/workflow/internal/token"
+	"github.com/Kiratopat-s/workflow/internal/twofactor"
 	"gorm.io/gorm"
 )
 
-
+var (
+	// ErrInvalidCredentials is returned for unknown usernames and wrong
+	// passwords alike, so logins do not reveal which usernames exist.
+	ErrInvalidCredentials    = errors.New("invalid username or password")
+	ErrPasswordResetRequired = errors.New("your password must be reset, use the link you were sent or ask for a new one")
+)
 
 type Service struct {
 	Repository Repository
+	Roles      role.Service
+	Tokens     token.Service
+	TwoFactor  twofactor.Service
+	Lockouts   lockout.Service
+	Passwords  password.Config
+	Mailer     mail.Sender
 	secret     string
+	// dummyHash is checked for unknown usernames, so they take as long to
+	// reject as wrong passwords.
+	dummyHash string
 }
 
-func NewService(db *gorm.DB, secret string) Service {
+func NewService(db *gorm.DB, secret string, mailer mail.Sender, lockouts lockout.Service, passwords password.Config) Service {
+	dummyHash, err := passwords.Hash("dummy password")
+	if err != nil {
+		log.Printf("Hashing the dummy password failed: %v\n", err)
+	}
 	return Service{
 		Repository: NewRepository(db),
+		Roles:      role.NewService(db),
+		Tokens:     token.NewService(db),
+		TwoFactor:  twofactor.NewService(db, lockouts),
+		Lockouts:   lockouts,
+		Passwords:  passwords,
+		Mailer:     mailer,
 		secret:     secret,
+		dummyHash:  dummyHash,
 	}
 }
 
-func (service Service) Login(req model.RequestLogin) (string, error) {
-	
+// Login checks the password of a user logging in from client. Failures are
+// counted per account and per address; once either is locked, Login
+// returns a lockout.LockedError without checking the password.
+func (service Service) Login(req model.RequestLogin, client auth.Client) (model.LoginResult, error) {
+	if err := service.Lockouts.Check(req.Username, client.IP); err != nil {
+		return model.LoginResult{}, err
+	}
+
 	user, err := service.Repository.FindOneByUsername(req.Username)
 	if err != nil {
-		return "", err
+		return model.LoginResult{}, err
+	}
+	ok, err := service.checkPassword(user, req.Password)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	if !ok {
+		if err := service.Lockouts.RecordFailure(req.Username, client.IP); err != nil {
+			return model.LoginResult{}, err
+		}
+		return model.LoginResult{}, ErrInvalidCredentials
 	}
-	if !checkPasswordHash(req.Password, user.Password) {
-		return "", errors.New("invalid password")
+	if !user.Active() {
+		return model.LoginResult{}, token.ErrAccountDeactivated
 	}
-	token, err := auth.CreateToken(user.ID,user.Username,user.FirstName,user.LastName,user.Position,user.PhotoLink)
-	
+	if user.PasswordResetRequired {
+		return model.LoginResult{}, ErrPasswordResetRequired
+	}
+
+	// Users with two-factor authentication finish at /login/2fa, which
+	// forgets their failures once the code is right too
+	if user.TOTPEnabledAt != nil {
+		challenge, err := service.TwoFactor.Challenge(user)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+		return model.LoginResult{MFARequired: true, MFAToken: challenge}, nil
+	}
+	if err := service.Lockouts.RecordSuccess(req.Username); err != nil {
+		return model.LoginResult{}, err
+	}
+
+	mustEnroll, err := service.TwoFactor.MustEnroll(user)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	pair, err := service.Tokens.Issue(user, client)
 	if err != nil {
-		return "", err
+		return model.LoginResult{}, err
 	}
-	return token, nil
+	return model.LoginResult{ResponseToken: pair, MFAEnrollmentRequired: mustEnroll}, nil
 }
 
+// Register creates an account with an invitation, which is used up. The
+// new user gets the invitation's role and position, not ones they pick,
+// and is mailed a link to verify their email address.
 func (service Service) Register(req model.RequestRegister) error {
-	fmt.Print("SVR || ",req.Username)
 	// Check if username is already taken
-	_, err := service.Repository.FindOneByUsername(req.Username)
+	existing, err := service.Repository.FindOneByUsername(req.Username)
+	if err != nil {
+		return err
+	}
+	if existing.Exists() {
+		return ErrUsernameTaken
+	}
+	taken, err := service.Repository.EmailTaken(req.Email, 0)
 	if err != nil {
-		return errors.New("username already taken")
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	// Check password strength
+	if err := service.Passwords.Validate(req.Password, req.Username); err != nil {
+		return err
 	}
 
 	// Hash password
-	hash, err := hashPassword(req.Password)
+	hash, err := service.Passwords.Hash(req.Password)
 	if err != nil {
 		return err
 	}
@@ -59,25 +142,50 @@ func (service Service) Register(req model.RequestRegister) error {
 	user := model.User{
 		Username: req.Username,
 		Password: hash,
-		Position: req.Position,
 		FirstName: req.FirstName,
 		LastName: req.LastName,
 		PhotoLink: req.PhotoLink,
+		Email: req.Email,
 	}
-	if err := service.Repository.Register(&user); err != nil {
+	err = service.Repository.Database.Transaction(func(tx *gorm.DB) error {
+		return service.acceptInvitation(tx, req.InvitationToken, &user)
+	})
+	if err != nil || user.EmailVerified() {
 		return err
 	}
+
+	// The user can ask for another link if this one gets lost
+	if err := service.mailVerificationLink(user); err != nil {
+		log.Printf("Creating the verification link of user %d failed: %v\n", user.ID, err)
+	}
 	return nil
 }
 
-func checkPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	fmt.Print(err)
-	return err == nil
-}
+// checkPassword reports whether plain is the password of user. A hash made
+// with outdated parameters is replaced, now that the password is known.
+// Unknown users are checked against a dummy hash and never match.
+func (service Service) checkPassword(user model.User, plain string) (bool, error) {
+	// Users provisioned by single sign-on have no password
+	hasPassword := user.Exists() && user.Password != ""
+	hash := user.Password
+	if !hasPassword {
+		hash = service.dummyHash
+	}
+	ok, rehash, err := service.Passwords.Verify(plain, hash)
+	if err != nil {
+		return false, err
+	}
+	if !ok || !hasPassword {
+		return false, nil
+	}
 
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	if rehash {
+		// The login goes on with the old hash if this fails
+		if newHash, err := service.Passwords.Hash(plain); err != nil {
+			log.Printf("Rehashing the password of user %d failed: %v\n", user.ID, err)
+		} else if err := service.Repository.UpdatePassword(user.ID, newHash); err != nil {
+			log.Printf("Rehashing the password of user %d failed: %v\n", user.ID, err)
+		}
+	}
+	return true, nil
 }
-
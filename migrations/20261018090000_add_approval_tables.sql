@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE approval_levels (
+    id                 bigserial PRIMARY KEY,
+    level              INT UNIQUE NOT NULL,
+    name               VARCHAR(100) NOT NULL,
+    approver_position  VARCHAR(100) NOT NULL,
+    min_total          INT NOT NULL
+);
+
+CREATE TABLE approval_steps (
+    id                 bigserial PRIMARY KEY,
+    item_id            BIGINT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    round              INT NOT NULL,
+    level              INT NOT NULL,
+    name               VARCHAR(100) NOT NULL,
+    approver_position  VARCHAR(100) NOT NULL,
+    status             VARCHAR(20) NOT NULL,
+    decided_by         INT,
+    decided_at         TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_approval_steps_item_id ON approval_steps (item_id);
+
+-- insert seed data
+INSERT INTO approval_levels (level, name, approver_position, min_total) VALUES (1, 'Team lead', 'TeamLead', 10000);
+INSERT INTO approval_levels (level, name, approver_position, min_total) VALUES (2, 'Department head', 'DepartmentHead', 50000);
+INSERT INTO approval_levels (level, name, approver_position, min_total) VALUES (3, 'Finance', 'Finance', 100000);
+
+-- +goose Down
+DROP TABLE approval_steps;
+DROP TABLE approval_levels;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE item_histories (
+    id          bigserial PRIMARY KEY,
+    item_id     BIGINT NOT NULL,
+    action      VARCHAR(20) NOT NULL,
+    old_value   TEXT,
+    new_value   TEXT,
+    reason      TEXT,
+    actor_id    INT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_item_histories_item_id ON item_histories (item_id);
+
+-- History is append-only
+CREATE RULE item_histories_no_update AS ON UPDATE TO item_histories DO INSTEAD NOTHING;
+CREATE RULE item_histories_no_delete AS ON DELETE TO item_histories DO INSTEAD NOTHING;
+
+-- +goose Down
+DROP TABLE item_histories;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE comments (
+    id          bigserial PRIMARY KEY,
+    item_id     BIGINT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    author_id   INT NOT NULL,
+    body        TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_comments_item_id ON comments (item_id);
+
+-- +goose Down
+DROP TABLE comments;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE delegations (
+    id            bigserial PRIMARY KEY,
+    delegator_id  INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id   INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at     TIMESTAMPTZ NOT NULL,
+    ends_at       TIMESTAMPTZ NOT NULL,
+    max_amount    INT,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at),
+    CHECK (delegator_id <> delegate_id)
+);
+
+CREATE INDEX idx_delegations_delegate_id ON delegations (delegate_id);
+CREATE INDEX idx_delegations_delegator_id ON delegations (delegator_id);
+
+ALTER TABLE item_histories ADD COLUMN on_behalf_of_id INT;
+ALTER TABLE approval_steps ADD COLUMN decided_on_behalf_of INT;
+
+-- +goose Down
+ALTER TABLE approval_steps DROP COLUMN decided_on_behalf_of;
+ALTER TABLE item_histories DROP COLUMN on_behalf_of_id;
+DROP TABLE delegations;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE items ADD COLUMN status_changed_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE items ADD COLUMN escalated_at TIMESTAMPTZ;
+ALTER TABLE items ADD COLUMN assignee_id INT;
+
+CREATE INDEX idx_items_status_status_changed_at ON items (status, status_changed_at);
+
+-- +goose Down
+DROP INDEX idx_items_status_status_changed_at;
+ALTER TABLE items DROP COLUMN assignee_id;
+ALTER TABLE items DROP COLUMN escalated_at;
+ALTER TABLE items DROP COLUMN status_changed_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE item_revisions (
+    id          bigserial PRIMARY KEY,
+    item_id     BIGINT NOT NULL,
+    revision    INT NOT NULL,
+    event       VARCHAR(20) NOT NULL,
+    title       VARCHAR(255) NOT NULL,
+    amount      INT NOT NULL,
+    quantity    INT NOT NULL,
+    created_by  INT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (item_id, revision)
+);
+
+-- Revisions are immutable
+CREATE RULE item_revisions_no_update AS ON UPDATE TO item_revisions DO INSTEAD NOTHING;
+CREATE RULE item_revisions_no_delete AS ON DELETE TO item_revisions DO INSTEAD NOTHING;
+
+-- +goose Down
+DROP TABLE item_revisions;
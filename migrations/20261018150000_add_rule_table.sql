@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE rules (
+    id          bigserial PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    expression  TEXT NOT NULL,
+    action      VARCHAR(20) NOT NULL,
+    route_to    VARCHAR(100),
+    priority    INT NOT NULL DEFAULT 0,
+    enabled     BOOLEAN NOT NULL DEFAULT true,
+    created_by  INT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE rules;
@@ -0,0 +1,56 @@
+-- +goose Up
+CREATE TABLE roles (
+    id           bigserial PRIMARY KEY,
+    name         VARCHAR(100) UNIQUE NOT NULL,
+    description  TEXT
+);
+
+CREATE TABLE permissions (
+    id           bigserial PRIMARY KEY,
+    name         VARCHAR(100) UNIQUE NOT NULL,
+    description  TEXT
+);
+
+CREATE TABLE role_permissions (
+    role_id        BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id  BIGINT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_roles (
+    user_id  INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id  BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+-- insert seed data
+INSERT INTO roles (name, description) VALUES ('Admin', 'Approves items and administers the system');
+INSERT INTO roles (name, description) VALUES ('User', 'Creates and follows their own requests');
+
+INSERT INTO permissions (name, description) VALUES ('items:create', 'Create purchase requests');
+INSERT INTO permissions (name, description) VALUES ('items:approve', 'Approve, reject and order any item');
+INSERT INTO permissions (name, description) VALUES ('items:read:any', 'Read any item and the history of deleted items');
+INSERT INTO permissions (name, description) VALUES ('items:delete:any', 'Delete items of other users');
+INSERT INTO permissions (name, description) VALUES ('items:override', 'Change read-only items with a justification');
+INSERT INTO permissions (name, description) VALUES ('comments:moderate', 'Edit and delete comments of other users');
+INSERT INTO permissions (name, description) VALUES ('delegations:manage', 'Revoke delegations of other users');
+INSERT INTO permissions (name, description) VALUES ('approval-levels:manage', 'Change the approval chain levels');
+INSERT INTO permissions (name, description) VALUES ('rules:manage', 'Manage auto-approval and routing rules');
+INSERT INTO permissions (name, description) VALUES ('roles:manage', 'Assign roles to users');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'Admin';
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'User' AND permissions.name = 'items:create';
+
+-- Existing admins keep their rights, everybody gets the User role
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id FROM users, roles WHERE roles.name = 'User';
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id FROM users, roles WHERE roles.name = 'Admin' AND users.position = 'Admin';
+
+-- +goose Down
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
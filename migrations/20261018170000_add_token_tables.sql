@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE refresh_tokens (
+    id              bigserial PRIMARY KEY,
+    user_id         INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash      VARCHAR(64) UNIQUE NOT NULL,
+    family_id       VARCHAR(64) NOT NULL,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    revoked_at      TIMESTAMPTZ,
+    replaced_by_id  BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE TABLE revoked_tokens (
+    jti         VARCHAR(64) PRIMARY KEY,
+    user_id     INT,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    revoked_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+INSERT INTO permissions (name, description) VALUES ('tokens:revoke', 'Revoke access tokens of any user');
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'Admin' AND permissions.name = 'tokens:revoke';
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'tokens:revoke';
+DROP TABLE revoked_tokens;
+DROP TABLE refresh_tokens;
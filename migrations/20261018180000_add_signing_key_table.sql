@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE signing_keys (
+    kid          VARCHAR(64) PRIMARY KEY,
+    algorithm    VARCHAR(10) NOT NULL,
+    private_key  TEXT NOT NULL,
+    public_key   TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    retired_at   TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ
+);
+
+INSERT INTO permissions (name, description) VALUES ('keys:manage', 'List and rotate token signing keys');
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'Admin' AND permissions.name = 'keys:manage';
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'keys:manage';
+DROP TABLE signing_keys;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE api_keys (
+    id            bigserial PRIMARY KEY,
+    user_id       INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          VARCHAR(100) NOT NULL,
+    prefix        VARCHAR(20) NOT NULL,
+    key_hash      VARCHAR(64) UNIQUE NOT NULL,
+    scopes        TEXT NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    last_used_at  TIMESTAMPTZ,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
+
+-- +goose Down
+DROP TABLE api_keys;
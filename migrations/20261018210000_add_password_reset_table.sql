@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE password_resets (
+    id          bigserial PRIMARY KEY,
+    user_id     INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  VARCHAR(64) UNIQUE NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_password_resets_user_id ON password_resets (user_id);
+
+-- +goose Down
+DROP TABLE password_resets;
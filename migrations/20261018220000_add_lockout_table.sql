@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE lockouts (
+    id               bigserial PRIMARY KEY,
+    kind             VARCHAR(20) NOT NULL,
+    subject          VARCHAR(255) NOT NULL,
+    failures         INT NOT NULL DEFAULT 0,
+    last_failure_at  TIMESTAMPTZ NOT NULL,
+    locked_until     TIMESTAMPTZ,
+    CONSTRAINT idx_lockouts_subject UNIQUE (kind, subject)
+);
+
+INSERT INTO permissions (name, description) VALUES ('lockouts:manage', 'View and clear login lockouts');
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'Admin' AND permissions.name = 'lockouts:manage';
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'lockouts:manage';
+DROP TABLE lockouts;
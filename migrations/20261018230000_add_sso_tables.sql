@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE user_identities (
+    id             bigserial PRIMARY KEY,
+    user_id        INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer         VARCHAR(255) NOT NULL,
+    subject        VARCHAR(255) NOT NULL,
+    last_login_at  TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT idx_user_identities_subject UNIQUE (issuer, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE sso_login_states (
+    id             bigserial PRIMARY KEY,
+    state_hash     VARCHAR(64) UNIQUE NOT NULL,
+    nonce          VARCHAR(64) NOT NULL,
+    code_verifier  VARCHAR(128) NOT NULL,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE sso_login_states;
+DROP TABLE user_identities;
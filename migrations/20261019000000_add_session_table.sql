@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE sessions (
+    id            bigserial PRIMARY KEY,
+    user_id       INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id     VARCHAR(64) UNIQUE NOT NULL,
+    device        VARCHAR(100) NOT NULL,
+    user_agent    TEXT NOT NULL,
+    ip            VARCHAR(45) NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    last_seen_at  TIMESTAMPTZ NOT NULL,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+-- Logins from before sessions were recorded
+INSERT INTO sessions (user_id, family_id, device, user_agent, ip, expires_at, last_seen_at, created_at)
+SELECT user_id, family_id, 'Unknown device', '', '', MAX(expires_at), MAX(created_at), MIN(created_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL AND expires_at > now()
+GROUP BY user_id, family_id;
+
+INSERT INTO permissions (name, description) VALUES ('sessions:manage', 'View and sign out sessions of any user');
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'Admin' AND permissions.name = 'sessions:manage';
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'sessions:manage';
+DROP TABLE sessions;
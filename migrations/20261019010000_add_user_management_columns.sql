@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+INSERT INTO permissions (name, description) VALUES ('users:manage', 'List, edit, deactivate and reactivate users and force password resets');
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'Admin' AND permissions.name = 'users:manage';
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'users:manage';
+ALTER TABLE users DROP COLUMN password_reset_required;
+ALTER TABLE users DROP COLUMN deactivated_at;
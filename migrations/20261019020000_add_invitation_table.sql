@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE invitations (
+    id           bigserial PRIMARY KEY,
+    token_hash   VARCHAR(64) UNIQUE NOT NULL,
+    email        TEXT NOT NULL DEFAULT '',
+    role_id      BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    position     TEXT NOT NULL DEFAULT '',
+    created_by   INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    accepted_at  TIMESTAMPTZ,
+    accepted_by  INT REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO permissions (name, description) VALUES ('invitations:manage', 'Invite users to register and revoke invitations');
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions WHERE roles.name = 'Admin' AND permissions.name = 'invitations:manage';
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'invitations:manage';
+DROP TABLE invitations;
@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Accounts from before verification keep creating items; usernames that
+-- are addresses become their email
+UPDATE users SET email = username
+WHERE username ~ '^[^@[:space:]]+@[^@[:space:]]+$'
+  AND lower(username) NOT IN (SELECT lower(username) FROM users GROUP BY lower(username) HAVING count(*) > 1);
+UPDATE users SET email_verified_at = now();
+
+CREATE UNIQUE INDEX idx_users_email ON users (lower(email)) WHERE email <> '';
+
+CREATE TABLE email_verifications (
+    id          bigserial PRIMARY KEY,
+    user_id     INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email       TEXT NOT NULL,
+    token_hash  VARCHAR(64) UNIQUE NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_email_verifications_user_id ON email_verifications (user_id);
+
+-- +goose Down
+DROP TABLE email_verifications;
+DROP INDEX idx_users_email;
+ALTER TABLE users DROP COLUMN email_verified_at;
+ALTER TABLE users DROP COLUMN email;
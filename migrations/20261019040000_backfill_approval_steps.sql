@@ -0,0 +1,12 @@
+-- +goose Up
+-- Items awaiting approval from before approval chains get their first round,
+-- which is otherwise opened when an item is submitted
+INSERT INTO approval_steps (item_id, round, level, name, approver_position, status)
+SELECT items.id, 1, approval_levels.level, approval_levels.name, approval_levels.approver_position, 'PENDING'
+FROM items
+JOIN approval_levels ON items.amount * items.quantity > approval_levels.min_total
+WHERE items.status IN ('SUBMITTED', 'PENDING')
+  AND NOT EXISTS (SELECT 1 FROM approval_steps WHERE approval_steps.item_id = items.id);
+
+-- +goose Down
+-- Backfilled steps are kept, they are indistinguishable from real ones